@@ -0,0 +1,120 @@
+package goping
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// AllowConfig restricts each group of endpoints to clients in the listed
+// CIDRs or addresses. An empty list allows everyone.
+type AllowConfig struct {
+	// /metrics
+	Metrics []string `yaml:"metrics"`
+	// The dashboard and read-only API endpoints
+	Status []string `yaml:"status"`
+	// Everything that changes state, plus snapshots
+	Admin []string `yaml:"admin"`
+}
+
+type accessList struct {
+	groups map[string][]netip.Prefix
+}
+
+func newAccessList(cfg AllowConfig) (*accessList, error) {
+	l := &accessList{groups: make(map[string][]netip.Prefix)}
+	for group, entries := range map[string][]string{
+		"metrics": cfg.Metrics,
+		"status":  cfg.Status,
+		"admin":   cfg.Admin,
+	} {
+		for _, e := range entries {
+			p, err := parsePrefix(e)
+			if err != nil {
+				return nil, fmt.Errorf("api.allow.%s: %w", group, err)
+			}
+			l.groups[group] = append(l.groups[group], p)
+		}
+	}
+	return l, nil
+}
+
+// parsePrefix accepts a CIDR or a single address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// endpointGroup classifies a request for the allow-lists. /health and /readyz
+// are left open for container and load balancer probes. Reads that return
+// target configuration count as admin.
+func endpointGroup(r *http.Request) string {
+	switch {
+	case r.URL.Path == "/health", r.URL.Path == "/readyz":
+		return ""
+	case r.URL.Path == "/metrics":
+		return "metrics"
+	case r.URL.Path == "/api/snapshot", r.URL.Path == "/api/targets":
+		return "admin"
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return "status"
+	default:
+		return "admin"
+	}
+}
+
+func (l *accessList) allowed(group string, addr netip.Addr) bool {
+	prefixes := l.groups[group]
+	if len(prefixes) == 0 {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// middleware rejects requests from clients outside the allow-list of the
+// endpoint's group. The client is the connection's peer; forwarded headers
+// are not trusted.
+func (l *accessList) middleware(next http.Handler) http.Handler {
+	if len(l.groups) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		group := endpointGroup(r)
+		if group == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil || !l.allowed(group, addr) {
+			logger.Debug("Rejected request from address not in allow-list", "remote", r.RemoteAddr, "group", group, "path", r.URL.Path)
+			writeError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
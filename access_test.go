@@ -0,0 +1,58 @@
+package goping
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessList(t *testing.T) {
+	l, err := newAccessList(AllowConfig{
+		Metrics: []string{"10.0.0.0/8"},
+		Admin:   []string{"192.0.2.10", " 2001:db8::/32 "},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := l.middleware(ok)
+
+	tests := []struct {
+		method, path, remote string
+		want                 int
+	}{
+		{"GET", "/metrics", "10.1.2.3:5000", http.StatusOK},
+		{"GET", "/metrics", "192.0.2.10:5000", http.StatusForbidden},
+		// IPv4-mapped IPv6 peers match IPv4 prefixes.
+		{"GET", "/metrics", "[::ffff:10.1.2.3]:5000", http.StatusOK},
+		{"GET", "/health", "203.0.113.1:5000", http.StatusOK},
+		{"GET", "/readyz", "203.0.113.1:5000", http.StatusOK},
+		// status has no list, so everyone may read.
+		{"GET", "/api/status", "203.0.113.1:5000", http.StatusOK},
+		{"GET", "/api/targets", "203.0.113.1:5000", http.StatusForbidden},
+		{"GET", "/api/snapshot", "192.0.2.10:5000", http.StatusOK},
+		{"POST", "/api/targets/web/pause", "192.0.2.11:5000", http.StatusForbidden},
+		{"POST", "/api/targets/web/pause", "[2001:db8::1]:5000", http.StatusOK},
+		{"DELETE", "/api/silences/x", "192.0.2.10:5000", http.StatusOK},
+		{"POST", "/api/run", "not-an-address", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path+" from "+tt.remote, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = tt.remote
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessListInvalid(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "example.com", ""} {
+		if _, err := newAccessList(AllowConfig{Status: []string{entry}}); err == nil {
+			t.Errorf("newAccessList accepted %q", entry)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package goping
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIConfig configures the API served on the metrics port. Endpoints that
+// trigger actions require Token as a bearer token and are disabled without it.
+// ReadOnly rejects every request that would change anything.
+type APIConfig struct {
+	Token    string      `yaml:"token"`
+	ReadOnly bool        `yaml:"read_only"`
+	Allow    AllowConfig `yaml:"allow"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("Failed to encode response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// requireToken rejects requests that don't carry the API token.
+func requireToken(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			writeError(w, http.StatusForbidden, "endpoint disabled: no API token configured")
+			return
+		}
+
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !tokenMatches(got, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func tokenMatches(got, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// readOnly rejects all but GET and HEAD requests, so the status API and
+// dashboard can be exposed without exposing management.
+func readOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeError(w, http.StatusForbidden, "goping is running in read-only mode")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func registerAPI(mux *http.ServeMux, cfg APIConfig, targets *targetSet) {
+	mux.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		list := targets.list()
+		statuses := make([]targetStatus, 0, len(list))
+		for _, t := range list {
+			statuses = append(statuses, t.status())
+		}
+		statuses, err := filterStatuses(w, r.URL.Query(), statuses)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	})
+
+	// POST /api/targets adds a target at runtime. The body is a target as it
+	// would appear in the config file, in JSON or YAML.
+	mux.HandleFunc("POST /api/targets", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var c CheckConfig
+		if err := yaml.Unmarshal(body, &c); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		t, err := targets.add(c)
+		if errors.Is(err, errDuplicateTarget) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, t.status())
+	}))
+
+	// GET /api/targets exports the configuration of every target, or only the
+	// runtime ones with ?runtime=true, in the format POST /api/targets takes.
+	mux.HandleFunc("GET /api/targets", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		runtimeOnly := r.URL.Query().Get("runtime") == "true"
+		var out struct {
+			Targets []CheckConfig `yaml:"targets"`
+		}
+		out.Targets = []CheckConfig{}
+		for _, t := range targets.list() {
+			if t.runtime || !runtimeOnly {
+				out.Targets = append(out.Targets, t.cfg)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/yaml")
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(out); err != nil {
+			logger.Error("Failed to encode targets", "error", err)
+		}
+		enc.Close()
+	}))
+
+	mux.HandleFunc("DELETE /api/targets/{name}", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if _, ok := targets.get(name); !ok {
+			writeError(w, http.StatusNotFound, "unknown target")
+			return
+		}
+
+		if err := targets.remove(name); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// GET /api/snapshot exports runtime state for `goping -restore`.
+	mux.HandleFunc("GET /api/snapshot", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(takeSnapshot(targets)); err != nil {
+			logger.Error("Failed to encode snapshot", "error", err)
+		}
+		enc.Close()
+	}))
+
+	// POST /api/run checks the given targets immediately and responds with
+	// the results once all have finished, for "verify after deploy" automation.
+	mux.HandleFunc("POST /api/run", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Targets []string `json:"targets"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(req.Targets) == 0 {
+			writeError(w, http.StatusBadRequest, "targets is required")
+			return
+		}
+
+		run := make([]*target, 0, len(req.Targets))
+		for _, name := range req.Targets {
+			t, ok := targets.get(name)
+			if !ok {
+				writeError(w, http.StatusNotFound, "unknown target "+name)
+				return
+			}
+			run = append(run, t)
+		}
+
+		results := make([]CheckResult, len(run))
+		var wg sync.WaitGroup
+		for i, t := range run {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], _ = t.runNow(r.Context())
+			}()
+		}
+		wg.Wait()
+		if r.Context().Err() != nil {
+			// The client has gone away; the canceled runs weren't recorded.
+			return
+		}
+
+		up := true
+		for _, res := range results {
+			up = up && res.Up
+		}
+
+		logger.Info("On-demand check run", "targets", req.Targets, "up", up)
+		writeJSON(w, http.StatusOK, map[string]any{"up": up, "results": results})
+	}))
+
+	mux.HandleFunc("GET /api/schedule", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, scheduleOf(targets))
+	})
+
+	mux.HandleFunc("GET /api/deploys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, deploys.list())
+	})
+	mux.HandleFunc("POST /api/deploys", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			deploy
+			Duration string `json:"duration"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid duration")
+			return
+		}
+		if req.Target != "" {
+			if _, ok := targets.get(req.Target); !ok {
+				writeError(w, http.StatusNotFound, "unknown target")
+				return
+			}
+		}
+
+		dep := deploys.start(req.deploy, d)
+		logger.Info("Deploy started", "id", dep.ID, "target", dep.Target, "until", dep.EndsAt, "created_by", dep.CreatedBy, "comment", dep.Comment)
+		writeJSON(w, http.StatusOK, dep)
+	}))
+	mux.HandleFunc("DELETE /api/deploys/{id}", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		if err := deploys.end(r.PathValue("id")); err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	mux.HandleFunc("POST /api/targets/{name}/pause", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		t, ok := targets.get(r.PathValue("name"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown target")
+			return
+		}
+
+		var req struct {
+			Duration string `json:"duration"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var d time.Duration
+		if req.Duration != "" {
+			var err error
+			if d, err = time.ParseDuration(req.Duration); err != nil || d <= 0 {
+				writeError(w, http.StatusBadRequest, "invalid duration")
+				return
+			}
+		}
+
+		t.pause(d)
+		writeJSON(w, http.StatusOK, t.status())
+	}))
+
+	mux.HandleFunc("POST /api/targets/{name}/resume", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		t, ok := targets.get(r.PathValue("name"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown target")
+			return
+		}
+
+		t.resume()
+		writeJSON(w, http.StatusOK, t.status())
+	}))
+
+	mux.HandleFunc("DELETE /api/targets/{name}/host-key", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		t, ok := targets.get(r.PathValue("name"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown target")
+			return
+		}
+		c, ok := t.checker.(*sshChecker)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "not an ssh check")
+			return
+		}
+		if c.pinned != "" {
+			writeError(w, http.StatusBadRequest, "host key is pinned with host_key_fingerprint")
+			return
+		}
+
+		if c.forgetHostKey() {
+			logger.Info("SSH host key forgotten", "check", t.cfg.Name)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	mux.HandleFunc("GET /api/events", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		since, err := parseTimeParam(q.Get("since"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		until, err := parseTimeParam(q.Get("until"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		all, err := store.Events(q["target"], since, until)
+		if err != nil {
+			logger.Error("Failed to query events", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to query events")
+			return
+		}
+		evs, err := filterEvents(w, q, all, targets)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, evs)
+	})
+
+	mux.HandleFunc("GET /api/results", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		since, err := parseTimeParam(q.Get("since"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		until, err := parseTimeParam(q.Get("until"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		p, err := parsePage(q)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		results, err := store.Results(q["target"], since, until)
+		if err != nil {
+			logger.Error("Failed to query results", "error", err)
+			writeError(w, http.StatusInternalServerError, "failed to query results")
+			return
+		}
+		writeJSON(w, http.StatusOK, paginate(w, results, p))
+	})
+
+	mux.HandleFunc("GET /api/history", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		since, err := parseTimeParam(q.Get("since"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		until, err := parseTimeParam(q.Get("until"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		h, err := history.query(q["target"], q.Get("resolution"), since, until)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, h)
+	})
+
+	mux.HandleFunc("GET /api/heatmap", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		since, err := parseTimeParam(q.Get("since"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		until, err := parseTimeParam(q.Get("until"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		h, err := history.heatmap(q["target"], q.Get("resolution"), since, until)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, h)
+	})
+
+	mux.HandleFunc("GET /api/silences", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, silences.list())
+	})
+
+	mux.HandleFunc("GET /api/silences/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s, ok := silences.get(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "silence not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+	})
+
+	mux.HandleFunc("POST /api/silences", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		var s silence
+		if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		id, err := silences.set(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		logger.Info("Silence set", "id", id, "created_by", s.CreatedBy, "comment", s.Comment)
+		writeJSON(w, http.StatusOK, map[string]string{"silenceID": id})
+	}))
+
+	mux.HandleFunc("DELETE /api/silences/{id}", requireToken(cfg.Token, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if err := silences.expire(id); err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		logger.Info("Silence expired", "id", id)
+		w.WriteHeader(http.StatusOK)
+	}))
+}
@@ -0,0 +1,80 @@
+package goping
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// arpChecker verifies that a device answers ARP on the local network, for
+// monitoring hosts that don't run any service.
+type arpChecker struct {
+	ip    net.IP
+	mac   net.HardwareAddr
+	iface string
+}
+
+func newARPChecker(cfg CheckConfig) (*arpChecker, error) {
+	ip := net.ParseIP(cfg.IP).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("check %q: ip must be an IPv4 address", cfg.Name)
+	}
+
+	c := &arpChecker{ip: ip, iface: cfg.Interface}
+	if cfg.MAC != "" {
+		mac, err := net.ParseMAC(cfg.MAC)
+		if err != nil {
+			return nil, fmt.Errorf("check %q: %w", cfg.Name, err)
+		}
+		c.mac = mac
+	}
+	return c, nil
+}
+
+func (c *arpChecker) check(ctx context.Context) error {
+	mac, err := arpResolve(ctx, c.ip, c.iface)
+	if err != nil {
+		return err
+	}
+
+	if c.mac != nil && !strings.EqualFold(mac.String(), c.mac.String()) {
+		return fmt.Errorf("%s is at %s, expected %s", c.ip, mac, c.mac)
+	}
+	return nil
+}
+
+// localInterfaceFor returns the interface whose subnet contains ip, or the
+// named interface if set, along with its IPv4 address.
+func localInterfaceFor(ip net.IP, name string) (*net.Interface, net.IP, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if name != "" && iface.Name != name {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok || ipnet.IP.To4() == nil {
+				continue
+			}
+			if name != "" || ipnet.Contains(ip) {
+				return iface, ipnet.IP.To4(), nil
+			}
+		}
+	}
+
+	if name != "" {
+		return nil, nil, fmt.Errorf("interface %s has no IPv4 address", name)
+	}
+	return nil, nil, fmt.Errorf("%s is not on a directly connected network", ip)
+}
@@ -0,0 +1,167 @@
+package goping
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	arpRequest = 1
+	arpReply   = 2
+
+	// ATF_COM in /proc/net/arp flags: the entry has a resolved hardware address.
+	arpFlagComplete = 0x2
+)
+
+// arpResolve sends an ARP request for ip and waits for the reply. Raw sockets
+// need CAP_NET_RAW; without it, the kernel is nudged into resolving ip and the
+// neighbour table is read instead.
+func arpResolve(ctx context.Context, ip net.IP, ifaceName string) (net.HardwareAddr, error) {
+	iface, src, err := localInterfaceFor(ip, ifaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	mac, err := arpProbe(ctx, ip, iface, src)
+	if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+		logger.Debug("No permission for raw ARP, falling back to the neighbour table", "ip", ip)
+		return arpNeighbour(ctx, ip, iface)
+	}
+	return mac, err
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+func arpProbe(ctx context.Context, ip net.IP, iface *net.Interface, src net.IP) (net.HardwareAddr, error) {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(htons(syscall.ETH_P_ARP)))
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(fd)
+
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(syscall.ETH_P_ARP), Ifindex: iface.Index}); err != nil {
+		return nil, err
+	}
+
+	// SOCK_DGRAM packet sockets add the Ethernet header for us.
+	pkt := make([]byte, 28)
+	binary.BigEndian.PutUint16(pkt[0:], 1)      // Ethernet
+	binary.BigEndian.PutUint16(pkt[2:], 0x0800) // IPv4
+	pkt[4], pkt[5] = 6, 4
+	binary.BigEndian.PutUint16(pkt[6:], arpRequest)
+	copy(pkt[8:], iface.HardwareAddr)
+	copy(pkt[14:], src)
+	copy(pkt[24:], ip)
+
+	dst := &syscall.SockaddrLinklayer{
+		Protocol: htons(syscall.ETH_P_ARP),
+		Ifindex:  iface.Index,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultCheckTimeout)
+	}
+
+	buf := make([]byte, 128)
+	for attempt := 0; time.Now().Before(deadline); attempt++ {
+		if err := syscall.Sendto(fd, pkt, 0, dst); err != nil {
+			return nil, err
+		}
+
+		// Wait up to a second per request, then ask again.
+		wait := min(time.Until(deadline), time.Second)
+		until := time.Now().Add(wait)
+		for time.Now().Before(until) {
+			tv := syscall.NsecToTimeval(time.Until(until).Nanoseconds())
+			if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+				return nil, err
+			}
+
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err != nil {
+				if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+					continue
+				}
+				return nil, err
+			}
+			if n < 28 || binary.BigEndian.Uint16(buf[6:]) != arpReply || !bytes.Equal(buf[14:18], ip) {
+				continue
+			}
+			return net.HardwareAddr(append([]byte(nil), buf[8:14]...)), nil
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	return nil, fmt.Errorf("no ARP reply from %s on %s", ip, iface.Name)
+}
+
+// arpNeighbour triggers resolution with a UDP datagram to the discard port and
+// polls /proc/net/arp. Unlike a raw probe this can report an entry the kernel
+// still considers valid for a device that has just left.
+func arpNeighbour(ctx context.Context, ip net.IP, iface *net.Interface) (net.HardwareAddr, error) {
+	conn, err := net.Dial("udp4", net.JoinHostPort(ip.String(), "9"))
+	if err != nil {
+		return nil, err
+	}
+	conn.Write([]byte{0})
+	conn.Close()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		mac, err := readARPTable(ip, iface.Name)
+		if err != nil || mac != nil {
+			return mac, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no ARP entry for %s on %s", ip, iface.Name)
+		case <-ticker.C:
+		}
+	}
+}
+
+func readARPTable(ip net.IP, ifaceName string) (net.HardwareAddr, error) {
+	f, err := os.Open("/proc/net/arp")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// IP address, HW type, Flags, HW address, Mask, Device
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[0] != ip.String() || fields[5] != ifaceName {
+			continue
+		}
+
+		var flags int
+		fmt.Sscanf(fields[2], "0x%x", &flags)
+		if flags&arpFlagComplete == 0 {
+			continue
+		}
+		return net.ParseMAC(fields[3])
+	}
+	return nil, scanner.Err()
+}
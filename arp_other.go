@@ -0,0 +1,13 @@
+//go:build !linux
+
+package goping
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+func arpResolve(ctx context.Context, ip net.IP, ifaceName string) (net.HardwareAddr, error) {
+	return nil, errors.New("arp checks are only supported on Linux")
+}
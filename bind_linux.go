@@ -0,0 +1,23 @@
+package goping
+
+import (
+	"net"
+	"syscall"
+)
+
+// bindToInterface uses SO_BINDTODEVICE so traffic leaves through iface even
+// when the routing table would pick another uplink. It requires CAP_NET_RAW
+// on older kernels.
+func bindToInterface(d *net.Dialer, iface *net.Interface) error {
+	d.Control = func(network, address string, c syscall.RawConn) error {
+		var opErr error
+		err := c.Control(func(fd uintptr) {
+			opErr = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, iface.Name)
+		})
+		if err != nil {
+			return err
+		}
+		return opErr
+	}
+	return nil
+}
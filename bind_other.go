@@ -0,0 +1,50 @@
+//go:build !linux
+
+package goping
+
+import (
+	"fmt"
+	"net"
+)
+
+// bindToInterface falls back to using the interface's address as the source
+// address, which only steers traffic if the host routes by source.
+func bindToInterface(d *net.Dialer, iface *net.Interface) error {
+	if d.LocalAddr != nil {
+		return nil
+	}
+
+	ip, err := interfaceAddr(iface)
+	if err != nil {
+		return err
+	}
+	d.LocalAddr = &net.TCPAddr{IP: ip}
+	return nil
+}
+
+// interfaceAddr returns the first IPv4 address of iface, or its first IPv6
+// address if it has none.
+func interfaceAddr(iface *net.Interface) (net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var v6 net.IP
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip4 := ipnet.IP.To4(); ip4 != nil {
+			return ip4, nil
+		}
+		if v6 == nil && !ipnet.IP.IsLinkLocalUnicast() {
+			v6 = ipnet.IP
+		}
+	}
+	if v6 == nil {
+		return nil, fmt.Errorf("interface %s has no usable address", iface.Name)
+	}
+	return v6, nil
+}
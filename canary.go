@@ -0,0 +1,161 @@
+package goping
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	canaryLatency = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goping_canary_latency_seconds",
+			Help: "Median latency of the last canary comparison, per variant",
+		},
+		[]string{"check", "variant"},
+	)
+
+	canaryStatusCode = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goping_canary_status_code",
+			Help: "HTTP status code returned in the last canary comparison, per variant (0 if the request failed)",
+		},
+		[]string{"check", "variant"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(canaryLatency)
+	prometheus.MustRegister(canaryStatusCode)
+}
+
+// canaryChecker probes a canary and a stable URL together and fails when
+// they diverge in status or latency, for watching progressive rollouts.
+type canaryChecker struct {
+	http      *httpChecker
+	canaryURL string
+	samples   int
+	maxDelta  time.Duration
+	maxRatio  float64
+}
+
+type canarySample struct {
+	status  int
+	latency time.Duration
+	err     error
+}
+
+func newCanaryChecker(cfg CheckConfig) (*canaryChecker, error) {
+	if cfg.CanaryURL == "" {
+		return nil, fmt.Errorf("check %q: canary_url is required", cfg.Name)
+	}
+	if set := pairedCheckFields(cfg); len(set) > 0 {
+		return nil, fmt.Errorf("check %q: %s can't be used with canary checks", cfg.Name, strings.Join(set, ", "))
+	}
+
+	hc, err := newHTTPChecker(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := cfg.Samples
+	if samples <= 0 {
+		samples = 1
+	}
+
+	return &canaryChecker{
+		http:      hc,
+		canaryURL: cfg.CanaryURL,
+		samples:   samples,
+		maxDelta:  cfg.MaxLatencyDelta,
+		maxRatio:  cfg.MaxLatencyRatio,
+	}, nil
+}
+
+func (c *canaryChecker) check(ctx context.Context) error {
+	var stable, canary canarySample
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		stable = c.sample(ctx, c.http.url)
+	}()
+	go func() {
+		defer wg.Done()
+		canary = c.sample(ctx, c.canaryURL)
+	}()
+	wg.Wait()
+
+	c.export("stable", stable)
+	c.export("canary", canary)
+
+	var problems []string
+	switch {
+	case stable.err != nil && canary.err != nil:
+		return fmt.Errorf("both variants failed: stable: %v; canary: %v", stable.err, canary.err)
+	case canary.err != nil:
+		return fmt.Errorf("canary failed: %w", canary.err)
+	case stable.err != nil:
+		return fmt.Errorf("stable failed: %w", stable.err)
+	case stable.status != canary.status:
+		problems = append(problems, fmt.Sprintf("status %d on canary vs %d on stable", canary.status, stable.status))
+	}
+
+	tr := traceFrom(ctx)
+	tr.assert("status_match", stable.status == canary.status)
+
+	delta := canary.latency - stable.latency
+	if c.maxDelta > 0 {
+		tr.assert("max_latency_delta", delta <= c.maxDelta)
+		if delta > c.maxDelta {
+			problems = append(problems, fmt.Sprintf("canary is %s slower than stable (max %s)", delta.Round(time.Millisecond), c.maxDelta))
+		}
+	}
+	if c.maxRatio > 0 && stable.latency > 0 {
+		ratio := float64(canary.latency) / float64(stable.latency)
+		tr.assert("max_latency_ratio", ratio <= c.maxRatio)
+		if ratio > c.maxRatio {
+			problems = append(problems, fmt.Sprintf("canary latency is %.2fx stable (max %.2fx)", ratio, c.maxRatio))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("canary diverges: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// sample fetches url c.samples times and reports the median latency and the
+// last status code. Any failed request or error status fails the sample, so
+// two variants failing alike don't pass for matching.
+func (c *canaryChecker) sample(ctx context.Context, url string) canarySample {
+	latencies := make([]time.Duration, 0, c.samples)
+	var s canarySample
+	for range c.samples {
+		res, err := c.http.fetch(ctx, url, false)
+		if err != nil {
+			return canarySample{err: err}
+		}
+		if res.status >= 400 {
+			return canarySample{status: res.status, err: statusError(res.status, fmt.Errorf("unexpected status code %d", res.status))}
+		}
+		s.status = res.status
+		latencies = append(latencies, res.latency)
+	}
+
+	slices.Sort(latencies)
+	s.latency = latencies[len(latencies)/2]
+	return s
+}
+
+func (c *canaryChecker) export(variant string, s canarySample) {
+	canaryStatusCode.WithLabelValues(c.http.name, variant).Set(float64(s.status))
+	if s.err == nil {
+		canaryLatency.WithLabelValues(c.http.name, variant).Set(s.latency.Seconds())
+	}
+}
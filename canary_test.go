@@ -0,0 +1,35 @@
+package goping
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCanaryCheckerRejectsSingleResponseOptions(t *testing.T) {
+	base := CheckConfig{Name: "canary-test", Type: "canary", URL: "https://api.example.com/", CanaryURL: "https://canary.example.com/"}
+	if _, err := newCanaryChecker(base); err != nil {
+		t.Fatalf("newCanaryChecker: %v", err)
+	}
+
+	tests := []struct {
+		field string
+		set   func(*CheckConfig)
+	}{
+		{"fallback_urls", func(c *CheckConfig) { c.Fallbacks = []string{"https://backup.example.com/"} }},
+		{"redirect", func(c *CheckConfig) { c.Redirect = &RedirectConfig{HTTPS: true} }},
+		{"metrics", func(c *CheckConfig) { c.Metrics = []JSONMetricConfig{{Name: "a", Path: "$.a"}} }},
+		{"freshness", func(c *CheckConfig) { c.Freshness = &FreshnessConfig{MaxAge: time.Hour} }},
+		{"throughput", func(c *CheckConfig) { c.Throughput = &ThroughputConfig{MinMbps: 1} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			cfg := base
+			tt.set(&cfg)
+			_, err := newCanaryChecker(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.field+" can't be used with canary checks") {
+				t.Errorf("newCanaryChecker = %v, want %s rejected", err, tt.field)
+			}
+		})
+	}
+}
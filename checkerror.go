@@ -0,0 +1,138 @@
+package goping
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var checkErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goping_check_errors_total",
+		Help: "Failed check runs by error category",
+	},
+	[]string{"check", "category"},
+)
+
+func init() {
+	prometheus.MustRegister(checkErrors)
+}
+
+// Error categories say what kind of failure a check hit.
+const (
+	categoryTimeout   = "timeout"
+	categoryCanceled  = "canceled"
+	categoryDNS       = "dns"
+	categoryConnect   = "connect"
+	categoryTLS       = "tls"
+	categoryStatus    = "status"
+	categoryAssertion = "assertion"
+	categoryPanic     = "panic"
+	categoryOther     = "other"
+)
+
+// CheckError describes why a check failed: the kind of failure, the phase
+// of the probe it happened in (dns, connect, tls, request, transfer or
+// assert, empty if unknown) and whether trying again could help. Retries
+// are only made for retriable errors.
+type CheckError struct {
+	Category  string `json:"category"`
+	Phase     string `json:"phase,omitempty"`
+	Retriable bool   `json:"retriable"`
+	Message   string `json:"message"`
+
+	Err error `json:"-"`
+}
+
+func (e *CheckError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CheckError) Unwrap() error {
+	return e.Err
+}
+
+func newCheckError(category, phase string, retriable bool, err error) *CheckError {
+	return &CheckError{Category: category, Phase: phase, Retriable: retriable, Message: err.Error(), Err: err}
+}
+
+// statusError is a response whose status code marks the target down. Server
+// errors, timeouts and throttling are worth retrying; other client errors
+// won't change.
+func statusError(code int, err error) *CheckError {
+	retriable := code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
+	return newCheckError(categoryStatus, "request", retriable, err)
+}
+
+// assertionError is a response that arrived but failed one of the check's
+// assertions.
+func assertionError(err error, retriable bool) *CheckError {
+	return newCheckError(categoryAssertion, "assert", retriable, err)
+}
+
+// classifyCheckError returns err as a CheckError, working out the category
+// and phase from the error chain when the checker didn't say. Errors it
+// doesn't recognise are kept retriable, as all errors were before they were
+// classified.
+func classifyCheckError(err error) *CheckError {
+	if err == nil {
+		return nil
+	}
+	var ce *CheckError
+	if errors.As(err, &ce) {
+		if ce.Err == err {
+			return ce
+		}
+		// Keep the context added by wrapping it, such as the attempts made.
+		return &CheckError{Category: ce.Category, Phase: ce.Phase, Retriable: ce.Retriable, Message: err.Error(), Err: err}
+	}
+
+	timeout := errors.Is(err, context.DeadlineExceeded)
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		timeout = true
+	}
+	category := func(c string) string {
+		if timeout {
+			return categoryTimeout
+		}
+		return c
+	}
+
+	phase := ""
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		phase = "request"
+	}
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuth x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var alertErr tls.AlertError
+	var recordErr tls.RecordHeaderError
+	switch {
+	case errors.As(err, &dnsErr):
+		// A name that doesn't exist won't start existing on a retry.
+		return newCheckError(category(categoryDNS), "dns", !dnsErr.IsNotFound, err)
+	case errors.As(err, &certErr), errors.As(err, &unknownAuth), errors.As(err, &hostErr), errors.As(err, &invalidCert):
+		return newCheckError(categoryTLS, "tls", false, err)
+	case errors.As(err, &alertErr), errors.As(err, &recordErr):
+		return newCheckError(category(categoryTLS), "tls", true, err)
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		return newCheckError(category(categoryConnect), "connect", true, err)
+	case errors.Is(err, context.Canceled):
+		return newCheckError(categoryCanceled, "", false, err)
+	case timeout:
+		return newCheckError(categoryTimeout, phase, true, err)
+	}
+	return newCheckError(categoryOther, phase, true, err)
+}
@@ -0,0 +1,469 @@
+package goping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	checkUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goping_check_up",
+			Help: "Whether the last run of a check succeeded (1) or failed (0)",
+		},
+		[]string{"check", "type"},
+	)
+
+	checkDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "goping_check_duration_seconds",
+			Help:    "Duration of check runs in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"check", "type"},
+	)
+
+	checkRunsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_check_runs_total",
+			Help: "Total number of check runs",
+		},
+		[]string{"check", "type", "status"},
+	)
+
+	checksMissed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_checks_missed_total",
+			Help: "Scheduled runs dropped because the previous run was still going",
+		},
+		[]string{"check"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(checkUp)
+	prometheus.MustRegister(checkDuration)
+	prometheus.MustRegister(checkRunsTotal)
+	prometheus.MustRegister(checksMissed)
+}
+
+// checker performs a single probe. A nil error means the target is up.
+type checker interface {
+	check(ctx context.Context) error
+}
+
+type target struct {
+	cfg     CheckConfig
+	checker checker
+
+	// runtime targets were added through the API rather than the config file.
+	runtime bool
+	stop    context.CancelFunc
+
+	mu      sync.Mutex
+	seen    bool
+	up      bool
+	since   time.Time
+	lastRun time.Time
+	lastErr error
+
+	lastDuration float64
+	lastFailure  time.Time
+
+	paused      bool
+	pausedUntil time.Time
+	resumedAt   time.Time
+
+	// running is set while a run is in progress, and queued when another is
+	// to follow it. manual marks a run started through the API; idle is
+	// closed once the run in progress finishes.
+	running    bool
+	queued     bool
+	manual     bool
+	idle       chan struct{}
+	runStarted time.Time
+	nextRun    time.Time
+	missed     int
+}
+
+// targetStatus is a point-in-time view of a target for the API and dashboard.
+type targetStatus struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Up        bool              `json:"up"`
+	Since     time.Time         `json:"since,omitzero"`
+	LastRun   time.Time         `json:"lastRun,omitzero"`
+	LastError string            `json:"lastError,omitempty"`
+
+	LastDuration float64   `json:"lastDurationSeconds"`
+	LastFailure  time.Time `json:"lastFailure,omitzero"`
+
+	Paused      bool      `json:"paused"`
+	PausedUntil time.Time `json:"pausedUntil,omitzero"`
+
+	Runtime bool `json:"runtime,omitempty"`
+
+	TLS *tlsInfo `json:"tls,omitempty"`
+
+	// Failure classifies LastError.
+	Failure *CheckError `json:"failure,omitempty"`
+}
+
+func newChecker(cfg CheckConfig) (checker, error) {
+	switch cfg.Type {
+	case "http":
+		return newHTTPChecker(cfg)
+	case "canary":
+		return newCanaryChecker(cfg)
+	case "diff":
+		return newDiffChecker(cfg)
+	case "ssh":
+		return newSSHChecker(cfg)
+	case "listen":
+		return newListenChecker(cfg)
+	case "arp":
+		return newARPChecker(cfg)
+	case "dns_propagation":
+		return newDNSPropagationChecker(cfg)
+	case "composite":
+		return newCompositeChecker(cfg)
+	default:
+		return nil, fmt.Errorf("check %q: unknown type %q", cfg.Name, cfg.Type)
+	}
+}
+
+func newTargets(cfg *Config) ([]*target, error) {
+	targets := make([]*target, 0, len(cfg.Targets))
+	for _, c := range cfg.Targets {
+		ch, err := newChecker(c)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, &target{cfg: c, checker: ch})
+	}
+
+	byName := targetsByName(targets)
+	for _, t := range targets {
+		if c, ok := t.checker.(*compositeChecker); ok {
+			if err := c.resolve(byName); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+func targetsByName(targets []*target) map[string]*target {
+	byName := make(map[string]*target, len(targets))
+	for _, t := range targets {
+		byName[t.cfg.Name] = t
+	}
+	return byName
+}
+
+// run checks the target and records the result. A run cut short because ctx
+// was canceled, by shutdown or a client going away, says nothing about the
+// target, so it is returned but not recorded.
+func (t *target) run(ctx context.Context) CheckResult {
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, t.cfg.Deadline)
+	defer cancel()
+
+	var tr *checkTrace
+	if t.cfg.Trace {
+		ctx, tr = withTrace(ctx)
+	}
+
+	start := timeSource.Now()
+	err := t.attempt(ctx)
+	elapsed := timeSource.Now().Sub(start)
+	duration := elapsed.Seconds()
+
+	if tr != nil {
+		tr.log(t.cfg, elapsed, err)
+	}
+
+	up := err == nil
+
+	result := CheckResult{Time: start, Check: t.cfg.Name, Type: t.cfg.Type, Tags: t.cfg.Tags, Up: up, Duration: duration}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if !up && parent.Err() != nil {
+		logger.Debug("Check run canceled, not recording it", "check", t.cfg.Name, "type", t.cfg.Type, "error", err)
+		return result
+	}
+	if errors.Is(err, errNoVerdict) {
+		// The run still happened, which the heartbeat wants to know.
+		t.mu.Lock()
+		t.lastRun = start
+		t.mu.Unlock()
+		logger.Debug("Check has no verdict yet, not recording it", "check", t.cfg.Name, "type", t.cfg.Type, "error", err)
+		return result
+	}
+
+	// Don't record downtime for a check that fails right after a suspend or
+	// clock step; the next run decides.
+	if !up && clock.settling(start) {
+		t.mu.Lock()
+		wasUp := t.seen && t.up
+		t.mu.Unlock()
+		if wasUp {
+			logger.Warn("Ignoring check failure shortly after a clock jump", "check", t.cfg.Name, "type", t.cfg.Type, "error", err)
+			return result
+		}
+	}
+
+	t.mu.Lock()
+	from := "unknown"
+	if t.seen {
+		from = stateName(t.up)
+	}
+	changed := t.seen && t.up != up || !t.seen && !up
+	if changed || !t.seen {
+		t.since = start
+	}
+	t.seen = true
+	t.up = up
+	t.lastRun = start
+	t.lastErr = err
+	t.lastDuration = duration
+	if !up {
+		t.lastFailure = start
+	}
+	since := t.since
+	t.mu.Unlock()
+
+	if to := stateName(up); from != to {
+		e := Event{Time: start, Check: t.cfg.Name, Type: t.cfg.Type, From: from, To: to}
+		if err != nil {
+			e.Error = err.Error()
+		}
+		if err := store.AddEvent(e); err != nil {
+			logger.Error("Failed to store event", "check", t.cfg.Name, "error", err)
+		}
+	}
+
+	if changed {
+		alerts.fire(t.alert(up, since, err))
+	}
+
+	history.record(result)
+	if err := store.AddResult(result); err != nil {
+		logger.Error("Failed to store result", "check", t.cfg.Name, "error", err)
+	}
+	for _, s := range sinks {
+		s.write(result)
+	}
+
+	status := "success"
+	if err != nil {
+		status = "failure"
+		ce := classifyCheckError(err)
+		checkUp.WithLabelValues(t.cfg.Name, t.cfg.Type).Set(0)
+		checkErrors.WithLabelValues(t.cfg.Name, ce.Category).Inc()
+		logger.Warn("Check failed", "check", t.cfg.Name, "type", t.cfg.Type, "error", err, "category", ce.Category, "phase", ce.Phase, "duration", duration)
+	} else {
+		checkUp.WithLabelValues(t.cfg.Name, t.cfg.Type).Set(1)
+		logger.Debug("Check succeeded", "check", t.cfg.Name, "type", t.cfg.Type, "duration", duration)
+	}
+
+	checkRunsTotal.WithLabelValues(t.cfg.Name, t.cfg.Type, status).Inc()
+	runStats.checksRun.Add(1)
+	if err != nil {
+		runStats.checkFailures.Add(1)
+	}
+	checkDuration.WithLabelValues(t.cfg.Name, t.cfg.Type).Observe(duration)
+
+	return result
+}
+
+func stateName(up bool) string {
+	if up {
+		return "up"
+	}
+	return "down"
+}
+
+// labels identifies the target in alerts and silences.
+func (t *target) labels() map[string]string {
+	labels := make(map[string]string, len(t.cfg.Tags)+3)
+	for k, v := range t.cfg.Tags {
+		labels[k] = v
+	}
+	labels["alertname"] = "CheckDown"
+	labels["check"] = t.cfg.Name
+	labels["type"] = t.cfg.Type
+	return labels
+}
+
+func (t *target) alert(up bool, since time.Time, err error) Alert {
+	a := Alert{
+		Labels:   t.labels(),
+		StartsAt: since,
+	}
+	if up {
+		a.Status = "resolved"
+		a.EndsAt = timeSource.Now()
+		a.Summary = fmt.Sprintf("Check %s recovered", t.cfg.Name)
+	} else {
+		a.Status = "firing"
+		a.Summary = fmt.Sprintf("Check %s is down: %v", t.cfg.Name, err)
+	}
+	return a
+}
+
+func (t *target) status() targetStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := targetStatus{
+		Name:    t.cfg.Name,
+		Type:    t.cfg.Type,
+		Tags:    t.cfg.Tags,
+		Up:      t.up,
+		Since:   t.since,
+		LastRun: t.lastRun,
+
+		LastDuration: t.lastDuration,
+		LastFailure:  t.lastFailure,
+
+		Paused:      t.paused,
+		PausedUntil: t.pausedUntil,
+
+		Runtime: t.runtime,
+	}
+	if t.lastErr != nil {
+		s.LastError = t.lastErr.Error()
+		s.Failure = classifyCheckError(t.lastErr)
+	}
+	if r, ok := t.checker.(tlsReporter); ok {
+		s.TLS = r.lastTLS()
+	}
+	return s
+}
+
+func (t *target) schedule(ctx context.Context) {
+	ticker := timeSource.NewTicker(t.cfg.Interval)
+	defer ticker.Stop()
+
+	t.setNextRun(timeSource.Now().Add(t.cfg.Interval))
+	if !t.isPaused(timeSource.Now()) {
+		t.tick(ctx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.Chan():
+			t.setNextRun(now.Add(t.cfg.Interval))
+			if t.isPaused(now) {
+				logger.Debug("Skipping paused check", "check", t.cfg.Name)
+				continue
+			}
+			t.tick(ctx)
+		}
+	}
+}
+
+// tick starts a run in the background, or applies the overlap policy if the
+// previous run hasn't finished, so slow targets never get concurrent probes.
+func (t *target) tick(ctx context.Context) {
+	t.mu.Lock()
+	if t.running && t.manual {
+		t.mu.Unlock()
+		logger.Debug("Check being run on demand, skipping scheduled run", "check", t.cfg.Name)
+		return
+	}
+	if t.running {
+		if t.cfg.Overlap == "queue" && !t.queued {
+			t.queued = true
+			t.mu.Unlock()
+			logger.Debug("Check still running, queueing next run", "check", t.cfg.Name)
+			return
+		}
+		t.missed++
+		t.mu.Unlock()
+		checksMissed.WithLabelValues(t.cfg.Name).Inc()
+		logger.Warn("Check still running, skipping scheduled run", "check", t.cfg.Name, "overlap", t.cfg.Overlap)
+		return
+	}
+	t.startRunLocked(false)
+	t.mu.Unlock()
+
+	go func() {
+		for {
+			t.runScheduled(ctx)
+
+			t.mu.Lock()
+			if !t.queued || ctx.Err() != nil {
+				t.endRunLocked()
+				t.mu.Unlock()
+				return
+			}
+			t.queued = false
+			t.runStarted = timeSource.Now()
+			t.mu.Unlock()
+		}
+	}()
+}
+
+func (t *target) startRunLocked(manual bool) {
+	t.running = true
+	t.manual = manual
+	t.idle = make(chan struct{})
+	t.runStarted = timeSource.Now()
+}
+
+func (t *target) endRunLocked() {
+	t.running, t.queued, t.manual = false, false, false
+	close(t.idle)
+}
+
+// runNow runs the target on demand. It waits for a run in progress to finish
+// first, so runs never overlap, and scheduled runs that come due meanwhile
+// are skipped without counting as missed.
+func (t *target) runNow(ctx context.Context) (CheckResult, error) {
+	t.mu.Lock()
+	for t.running {
+		idle := t.idle
+		t.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return CheckResult{}, ctx.Err()
+		case <-idle:
+		}
+		t.mu.Lock()
+	}
+	t.startRunLocked(true)
+	t.mu.Unlock()
+
+	defer func() {
+		t.mu.Lock()
+		t.endRunLocked()
+		t.mu.Unlock()
+	}()
+	return t.run(ctx), nil
+}
+
+func (t *target) setNextRun(next time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextRun = next
+}
+
+// runScheduled runs the target from its scheduler, which keeps going even if
+// recording the result panics.
+func (t *target) runScheduled(ctx context.Context) {
+	defer recoverPanic("scheduler", "check", t.cfg.Name)
+	t.run(ctx)
+}
@@ -0,0 +1,80 @@
+package goping
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	clockCheckInterval = time.Second
+
+	// clockJumpThreshold is how far the wall clock may drift from the
+	// monotonic clock between two readings before it counts as a jump.
+	clockJumpThreshold = 5 * time.Second
+
+	// clockSettlePeriod is how long after a jump failures are not trusted,
+	// since after a suspend the network usually needs a moment to come back.
+	clockSettlePeriod = time.Minute
+)
+
+var clockJumps = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "goping_clock_jumps_total",
+		Help: "Wall clock jumps detected, from NTP steps or suspend and resume",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(clockJumps)
+}
+
+var clock = &clockMonitor{}
+
+// clockMonitor detects the wall clock jumping relative to the monotonic
+// clock. Scheduling itself uses monotonic time and is unaffected, but checks
+// run right after a resume tend to fail for reasons that aren't real.
+type clockMonitor struct {
+	mu       sync.Mutex
+	lastJump time.Time
+}
+
+func (c *clockMonitor) run(ctx context.Context) {
+	ticker := time.NewTicker(clockCheckInterval)
+	defer ticker.Stop()
+
+	prev := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := time.Now()
+			// Round(0) strips the monotonic reading, leaving wall time.
+			wall := now.Round(0).Sub(prev.Round(0))
+			mono := now.Sub(prev)
+			prev = now
+
+			if skew := wall - mono; skew > clockJumpThreshold || skew < -clockJumpThreshold {
+				clockJumps.Inc()
+				logger.Warn("Wall clock jumped, ignoring new failures while things settle", "jump", skew.Round(time.Millisecond), "settle", clockSettlePeriod)
+
+				// Stamped on timeSource, which the times given to
+				// settling come from.
+				c.mu.Lock()
+				c.lastJump = timeSource.Now()
+				c.mu.Unlock()
+			}
+		}
+	}
+}
+
+// settling reports whether now is shortly after a clock jump.
+func (c *clockMonitor) settling(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return !c.lastJump.IsZero() && now.Sub(c.lastJump) < clockSettlePeriod
+}
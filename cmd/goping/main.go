@@ -0,0 +1,9 @@
+// Command goping is an uptime monitor. See the README for its flags and
+// config.
+package main
+
+import "github.com/alexraskin/goping"
+
+func main() {
+	goping.Main()
+}
@@ -0,0 +1,323 @@
+package goping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// compositeChecker derives its status from the last results of other checks.
+//
+// Expressions support check names, !, &&, || and parentheses, plus
+// atleast(n, expr, ...) which is true when at least n arguments are true:
+//
+//	atleast(2, api-us, api-eu, api-ap) && !maintenance
+type compositeChecker struct {
+	name    string
+	expr    exprNode
+	targets map[string]*target
+}
+
+func newCompositeChecker(cfg CheckConfig) (*compositeChecker, error) {
+	if cfg.Expression == "" {
+		return nil, fmt.Errorf("check %q: expression is required", cfg.Name)
+	}
+
+	expr, err := parseExpr(cfg.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("check %q: %w", cfg.Name, err)
+	}
+
+	return &compositeChecker{name: cfg.Name, expr: expr}, nil
+}
+
+// resolve binds the check names referenced by the expression to targets.
+func (c *compositeChecker) resolve(targets map[string]*target) error {
+	refs := make(map[string]*target)
+	for _, name := range c.expr.names(nil) {
+		if name == c.name {
+			return fmt.Errorf("check %q: expression references itself", c.name)
+		}
+		t, ok := targets[name]
+		if !ok {
+			return fmt.Errorf("check %q: expression references unknown check %q", c.name, name)
+		}
+		refs[name] = t
+	}
+	c.targets = refs
+	return nil
+}
+
+func (c *compositeChecker) references(name string) bool {
+	_, ok := c.targets[name]
+	return ok
+}
+
+// errNoVerdict is returned while a check can't tell whether its target is up.
+// Such runs are not recorded.
+var errNoVerdict = errors.New("no verdict")
+
+// check evaluates the expression once every referenced check has run. A
+// paused check counts with the state it had when it was paused.
+func (c *compositeChecker) check(ctx context.Context) error {
+	var waiting []string
+	for name, t := range c.targets {
+		t.mu.Lock()
+		seen := t.seen
+		t.mu.Unlock()
+		if !seen {
+			waiting = append(waiting, name)
+		}
+	}
+	if len(waiting) > 0 {
+		sort.Strings(waiting)
+		err := fmt.Errorf("%w: waiting for the first run of %s", errNoVerdict, strings.Join(waiting, ", "))
+		return newCheckError(categoryOther, "", false, err)
+	}
+
+	downSet := make(map[string]bool)
+	status := func(name string) bool {
+		t := c.targets[name]
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if !t.up {
+			downSet[name] = true
+		}
+		return t.up
+	}
+
+	if c.expr.eval(status) {
+		return nil
+	}
+
+	down := make([]string, 0, len(downSet))
+	for name := range downSet {
+		down = append(down, name)
+	}
+	sort.Strings(down)
+	return fmt.Errorf("expression is false (down: %s)", strings.Join(down, ", "))
+}
+
+type exprNode interface {
+	eval(status func(string) bool) bool
+	names(acc []string) []string
+}
+
+type identNode string
+
+func (n identNode) eval(status func(string) bool) bool { return status(string(n)) }
+func (n identNode) names(acc []string) []string        { return append(acc, string(n)) }
+
+type notNode struct{ x exprNode }
+
+func (n notNode) eval(status func(string) bool) bool { return !n.x.eval(status) }
+func (n notNode) names(acc []string) []string        { return n.x.names(acc) }
+
+type andNode struct{ x, y exprNode }
+
+func (n andNode) eval(status func(string) bool) bool {
+	// Evaluate both sides so every down check is reported.
+	x, y := n.x.eval(status), n.y.eval(status)
+	return x && y
+}
+func (n andNode) names(acc []string) []string { return n.y.names(n.x.names(acc)) }
+
+type orNode struct{ x, y exprNode }
+
+func (n orNode) eval(status func(string) bool) bool {
+	x, y := n.x.eval(status), n.y.eval(status)
+	return x || y
+}
+func (n orNode) names(acc []string) []string { return n.y.names(n.x.names(acc)) }
+
+type atLeastNode struct {
+	n    int
+	args []exprNode
+}
+
+func (n atLeastNode) eval(status func(string) bool) bool {
+	count := 0
+	for _, arg := range n.args {
+		if arg.eval(status) {
+			count++
+		}
+	}
+	return count >= n.n
+}
+
+func (n atLeastNode) names(acc []string) []string {
+	for _, arg := range n.args {
+		acc = arg.names(acc)
+	}
+	return acc
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+}
+
+func parseExpr(s string) (exprNode, error) {
+	tokens, err := tokenizeExpr(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in expression", p.tokens[p.pos])
+	}
+	return node, nil
+}
+
+func tokenizeExpr(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ',' || r == '!':
+			tokens = append(tokens, string(r))
+			i++
+		case strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||"):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		case isIdentChar(r):
+			j := i
+			for j < len(s) && isIdentChar(rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q in expression", r)
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentChar(r rune) bool {
+	return r == '-' || r == '_' || r == '.' || r == ':' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *exprParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		if got == "" {
+			return fmt.Errorf("expected %q, got end of expression", tok)
+		}
+		return fmt.Errorf("expected %q, got %q", tok, got)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = orNode{x, y}
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = andNode{x, y}
+	}
+	return x, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek() == "!" {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok {
+	case "":
+		return nil, errors.New("unexpected end of expression")
+	case "(":
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case ")", ",", "&&", "||":
+		return nil, fmt.Errorf("unexpected %q in expression", tok)
+	case "atleast":
+		if p.peek() == "(" {
+			return p.parseAtLeast()
+		}
+	}
+	return identNode(tok), nil
+}
+
+func (p *exprParser) parseAtLeast() (exprNode, error) {
+	p.next() // (
+	n, err := strconv.Atoi(p.next())
+	if err != nil || n < 0 {
+		return nil, errors.New("atleast: first argument must be a non-negative integer")
+	}
+
+	node := atLeastNode{n: n}
+	for p.peek() == "," {
+		p.next()
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		node.args = append(node.args, arg)
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if len(node.args) < n {
+		return nil, fmt.Errorf("atleast(%d, ...) has only %d arguments", n, len(node.args))
+	}
+	return node, nil
+}
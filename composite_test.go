@@ -0,0 +1,81 @@
+package goping
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseExpr(t *testing.T) {
+	tests := []struct {
+		expr  string
+		down  []string
+		want  bool
+		names []string
+	}{
+		{"api", nil, true, []string{"api"}},
+		{"api", []string{"api"}, false, []string{"api"}},
+		{"!api", []string{"api"}, true, []string{"api"}},
+		{"!!api", nil, true, []string{"api"}},
+		{"a && b", []string{"b"}, false, []string{"a", "b"}},
+		{"a || b", []string{"b"}, true, []string{"a", "b"}},
+		{"a || b", []string{"a", "b"}, false, []string{"a", "b"}},
+		// && binds tighter than ||.
+		{"a || b && c", []string{"b"}, true, []string{"a", "b", "c"}},
+		{"(a || b) && c", []string{"a", "b"}, false, []string{"a", "b", "c"}},
+		{"atleast(2, a, b, c)", []string{"a"}, true, []string{"a", "b", "c"}},
+		{"atleast(2, a, b, c)", []string{"a", "c"}, false, []string{"a", "b", "c"}},
+		{"atleast(0)", nil, true, nil},
+		{"atleast(1, a && b, c)", []string{"c"}, true, []string{"a", "b", "c"}},
+		{"atleast(2, api-us, api-eu, api-ap) && !maintenance", []string{"api-eu", "maintenance"}, true, []string{"api-us", "api-eu", "api-ap", "maintenance"}},
+		// atleast is only a function when called.
+		{"atleast", nil, true, []string{"atleast"}},
+		{"db.primary:5432 && cache_1", nil, true, []string{"db.primary:5432", "cache_1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			node, err := parseExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("parseExpr(%q): %v", tt.expr, err)
+			}
+			got := node.eval(func(name string) bool { return !slices.Contains(tt.down, name) })
+			if got != tt.want {
+				t.Errorf("eval with %v down = %t, want %t", tt.down, got, tt.want)
+			}
+			if names := node.names(nil); !slices.Equal(names, tt.names) {
+				t.Errorf("names = %q, want %q", names, tt.names)
+			}
+		})
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "unexpected end of expression"},
+		{"a &&", "unexpected end of expression"},
+		{"a b", `unexpected "b"`},
+		{"(a || b", `expected ")", got end of expression`},
+		{"a)", `unexpected ")"`},
+		{"&& a", `unexpected "&&"`},
+		{"a & b", `unexpected character '&'`},
+		{"a = b", `unexpected character '='`},
+		{"atleast(x, a)", "first argument must be a non-negative integer"},
+		{"atleast(-1, a)", "first argument must be a non-negative integer"},
+		{"atleast(3, a, b)", "atleast(3, ...) has only 2 arguments"},
+		{"atleast(1, a b)", `expected ")", got "b"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseExpr(tt.expr)
+			if err == nil {
+				t.Fatalf("parseExpr(%q) succeeded, want an error containing %q", tt.expr, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseExpr(%q) = %q, want an error containing %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
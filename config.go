@@ -0,0 +1,189 @@
+package goping
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultCheckInterval = 1 * time.Minute
+	defaultCheckTimeout  = 10 * time.Second
+	defaultRetryBackoff  = 1 * time.Second
+)
+
+type Config struct {
+	Version    int              `yaml:"version"`
+	Targets    []CheckConfig    `yaml:"targets"`
+	Notifiers  []NotifierConfig `yaml:"notifiers"`
+	MassOutage MassOutageConfig `yaml:"mass_outage"`
+	Heartbeat  HeartbeatConfig  `yaml:"heartbeat"`
+	Events     EventsConfig     `yaml:"events"`
+	Storage    StorageConfig    `yaml:"storage"`
+	History    HistoryConfig    `yaml:"history"`
+	Sinks      SinksConfig      `yaml:"sinks"`
+	API        APIConfig        `yaml:"api"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+
+	Maintenance []MaintenanceConfig `yaml:"maintenance"`
+}
+
+type CheckConfig struct {
+	Name     string            `yaml:"name"`
+	Type     string            `yaml:"type"`
+	Interval time.Duration     `yaml:"interval"`
+	Timeout  time.Duration     `yaml:"timeout"`
+	Tags     map[string]string `yaml:"tags,omitempty"`
+
+	// Failed attempts are retried up to Retries times, waiting RetryBackoff
+	// and doubling it each time. Timeout applies to each attempt and Deadline
+	// to the whole run, including waits.
+	Retries      int           `yaml:"retries,omitempty"`
+	RetryBackoff time.Duration `yaml:"retry_backoff,omitempty"`
+	Deadline     time.Duration `yaml:"deadline,omitempty"`
+
+	// Overlap is what happens when a run is due while the previous one is
+	// still going: "skip" (default) drops it, "queue" runs it afterwards.
+	Overlap string `yaml:"overlap,omitempty"`
+
+	// Outgoing connections are bound to this local IP and/or interface.
+	SourceAddress string `yaml:"source_address,omitempty"`
+	Interface     string `yaml:"interface,omitempty"`
+
+	// ResolveInterval makes HTTP checks resolve their host themselves and
+	// re-resolve it this often, regardless of the DNS TTL.
+	ResolveInterval time.Duration `yaml:"resolve_interval,omitempty"`
+
+	// Log a timing breakdown of every run of this check.
+	Trace bool `yaml:"trace,omitempty"`
+
+	// http
+	URL        string             `yaml:"url,omitempty"`
+	Fallbacks  []string           `yaml:"fallback_urls,omitempty"`
+	Method     string             `yaml:"method,omitempty"`
+	Throughput *ThroughputConfig  `yaml:"throughput,omitempty"`
+	Metrics    []JSONMetricConfig `yaml:"metrics,omitempty"`
+	Redirect   *RedirectConfig    `yaml:"redirect,omitempty"`
+	Freshness  *FreshnessConfig   `yaml:"freshness,omitempty"`
+
+	// canary, alongside the http fields for the stable URL
+	CanaryURL       string        `yaml:"canary_url,omitempty"`
+	Samples         int           `yaml:"samples,omitempty"`
+	MaxLatencyDelta time.Duration `yaml:"max_latency_delta,omitempty"`
+	MaxLatencyRatio float64       `yaml:"max_latency_ratio,omitempty"`
+
+	// diff, alongside the http fields for the first URL
+	CompareURL  string   `yaml:"compare_url,omitempty"`
+	JSON        bool     `yaml:"json,omitempty"`
+	IgnorePaths []string `yaml:"ignore_paths,omitempty"`
+
+	// ssh
+	Address            string `yaml:"address,omitempty"`
+	HostKeyFingerprint string `yaml:"host_key_fingerprint,omitempty"`
+
+	// listen
+	Port     int    `yaml:"port,omitempty"`
+	Protocol string `yaml:"protocol,omitempty"`
+
+	// arp
+	IP  string `yaml:"ip,omitempty"`
+	MAC string `yaml:"mac,omitempty"`
+
+	// dns_propagation
+	Record     string   `yaml:"record,omitempty"`
+	RecordType string   `yaml:"record_type,omitempty"`
+	Resolvers  []string `yaml:"resolvers,omitempty"`
+	Expected   []string `yaml:"expected,omitempty"`
+
+	// composite
+	Expression string `yaml:"expression,omitempty"`
+}
+
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return parseConfig(data)
+}
+
+// parseConfig decodes and validates a config, upgrading older versions in
+// memory with a warning to run migrate-config.
+func parseConfig(data []byte) (*Config, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	from, notes, err := migrateConfig(&root)
+	if err != nil {
+		return nil, err
+	}
+	if from != currentConfigVersion {
+		logger.Warn("Config uses an old format, run `goping migrate-config` to upgrade it", "version", from, "current", currentConfigVersion, "deprecated", notes)
+	}
+
+	var cfg Config
+	if err := root.Decode(&cfg); err != nil && root.Kind != 0 {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range cfg.Targets {
+		c := &cfg.Targets[i]
+		if c.Name == "" {
+			return nil, fmt.Errorf("check %d: name is required", i)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("check %q: duplicate name", c.Name)
+		}
+		names[c.Name] = true
+
+		if err := applyCheckDefaults(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return &cfg, nil
+}
+
+func applyCheckDefaults(c *CheckConfig) error {
+	if c.Interval <= 0 {
+		c.Interval = defaultCheckInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = min(defaultCheckTimeout, c.Interval)
+	}
+	if c.Timeout > c.Interval {
+		return fmt.Errorf("check %q: timeout %s is longer than interval %s", c.Name, c.Timeout, c.Interval)
+	}
+	if c.Retries < 0 {
+		return fmt.Errorf("check %q: retries must not be negative", c.Name)
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = defaultRetryBackoff
+	}
+	switch c.Overlap {
+	case "":
+		c.Overlap = "skip"
+	case "skip", "queue":
+	default:
+		return fmt.Errorf("check %q: overlap must be skip or queue", c.Name)
+	}
+
+	// Without retries a run takes at most one timeout, as it always has.
+	// With them, the run as a whole must finish before the next is due.
+	if c.Deadline > c.Interval {
+		return fmt.Errorf("check %q: deadline %s is longer than interval %s", c.Name, c.Deadline, c.Interval)
+	}
+	if c.Deadline <= 0 {
+		c.Deadline = c.Timeout
+		if c.Retries > 0 {
+			c.Deadline = c.Interval
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package goping
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyCheckDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     CheckConfig
+		want    CheckConfig
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			want: CheckConfig{Interval: time.Minute, Timeout: 10 * time.Second, RetryBackoff: time.Second, Overlap: "skip", Deadline: 10 * time.Second},
+		},
+		{
+			name: "short interval",
+			cfg:  CheckConfig{Interval: 5 * time.Second},
+			want: CheckConfig{Interval: 5 * time.Second, Timeout: 5 * time.Second, RetryBackoff: time.Second, Overlap: "skip", Deadline: 5 * time.Second},
+		},
+		{
+			name: "retries run until the interval",
+			cfg:  CheckConfig{Interval: 30 * time.Second, Timeout: 5 * time.Second, Retries: 2},
+			want: CheckConfig{Interval: 30 * time.Second, Timeout: 5 * time.Second, Retries: 2, RetryBackoff: time.Second, Overlap: "skip", Deadline: 30 * time.Second},
+		},
+		{
+			name: "timeout equal to interval",
+			cfg:  CheckConfig{Interval: 30 * time.Second, Timeout: 30 * time.Second, Overlap: "queue"},
+			want: CheckConfig{Interval: 30 * time.Second, Timeout: 30 * time.Second, RetryBackoff: time.Second, Overlap: "queue", Deadline: 30 * time.Second},
+		},
+		{
+			name:    "timeout longer than interval",
+			cfg:     CheckConfig{Interval: 30 * time.Second, Timeout: time.Minute},
+			wantErr: "timeout 1m0s is longer than interval 30s",
+		},
+		{
+			name:    "deadline longer than interval",
+			cfg:     CheckConfig{Interval: 30 * time.Second, Deadline: time.Minute},
+			wantErr: "deadline 1m0s is longer than interval 30s",
+		},
+		{
+			name:    "negative retries",
+			cfg:     CheckConfig{Retries: -1},
+			wantErr: "retries must not be negative",
+		},
+		{
+			name:    "bad overlap",
+			cfg:     CheckConfig{Overlap: "parallel"},
+			wantErr: "overlap must be skip or queue",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			err := applyCheckDefaults(&cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyCheckDefaults = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyCheckDefaults: %v", err)
+			}
+			if cfg.Interval != tt.want.Interval || cfg.Timeout != tt.want.Timeout || cfg.Retries != tt.want.Retries ||
+				cfg.RetryBackoff != tt.want.RetryBackoff || cfg.Overlap != tt.want.Overlap || cfg.Deadline != tt.want.Deadline {
+				t.Errorf("applyCheckDefaults gave %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package goping
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(templateFuncs).Funcs(template.FuncMap{
+	"availability": func(check string) string {
+		a, ok := history.availability(check)
+		if !ok {
+			return "-"
+		}
+		return fmt.Sprintf("%.3f%%", a*100)
+	},
+	"mul100": func(f float64) float64 { return f * 100 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>goping</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+.up { color: #080; } .down { color: #c00; }
+.error { color: #c00; }
+.gantt td.lane { position: relative; width: 40em; padding: 0; }
+.gantt .bar { position: absolute; top: 25%; height: 50%; }
+.gantt .past { background: #8a8; } .gantt .next { background: #ccc; }
+.gantt .running { background: #48c; } .gantt .overrun { background: #c44; }
+.gantt .now { position: absolute; top: 0; bottom: 0; border-left: 1px dashed #000; }
+</style>
+</head>
+<body>
+<h1>goping</h1>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+{{if and .TokenSet (not .ReadOnly)}}{{if .Authorized}}<form method="post" action="/logout"><button>Sign out</button></form>
+{{else}}<form method="post" action="/login"><label>API token <input type="password" name="token" required></label> <button>Sign in</button></form>{{end}}{{end}}
+
+<h2>Checks</h2>
+<table>
+<tr><th>Name</th><th>Type</th><th>Status</th><th>For</th><th>Availability</th><th>Last run</th><th>Error</th><th></th></tr>
+{{range .Targets}}
+<tr>
+<td>{{.Name}}</td><td>{{.Type}}</td>
+<td>{{if .Up}}<span class="up">up</span>{{else}}<span class="down">down</span>{{end}}{{if .Paused}} (paused{{if not .PausedUntil.IsZero}} until {{time .PausedUntil}}{{end}}){{end}}</td>
+<td>{{since .Since}}</td><td>{{availability .Name}}</td><td>{{since .LastRun}}</td><td>{{.LastError}}</td>
+<td>{{if and $.Authorized (not $.ReadOnly)}}{{if .Paused}}<form method="post" action="/targets/{{.Name}}/resume"><button>Resume</button></form>
+{{else}}<form method="post" action="/targets/{{.Name}}/pause"><input name="duration" size="4" placeholder="1h"> <button>Pause</button></form>{{end}}{{end}}</td>
+</tr>
+{{else}}
+<tr><td colspan="8">No checks configured</td></tr>
+{{end}}
+</table>
+
+<h2>Schedule</h2>
+<table class="gantt">
+<tr><th>Name</th><th>Interval</th><th>Last duration</th><th>Load</th><th>Backlog</th><th>Missed</th><th>{{.ScheduleRange}}</th></tr>
+{{range .Schedule}}
+<tr>
+<td>{{.Name}}</td><td>{{humanizeDuration .Interval}}</td><td>{{printf "%.3fs" .LastDuration}}</td>
+<td>{{if ge .Load 1.0}}<span class="down">{{printf "%.0f%%" (mul100 .Load)}}</span>{{else}}{{printf "%.0f%%" (mul100 .Load)}}{{end}}</td>
+<td>{{.Backlog}}</td><td>{{.Missed}}</td>
+<td class="lane"><div class="now" style="left: {{$.NowPercent}}%"></div>{{range .Bars}}<div class="bar {{.Kind}}" style="left: {{.Left}}%; width: {{.Width}}%"></div>{{end}}</td>
+</tr>
+{{end}}
+</table>
+
+<h2>Silences</h2>
+<table>
+<tr><th>State</th><th>Matchers</th><th>Starts</th><th>Ends</th><th>Created by</th><th>Comment</th><th></th></tr>
+{{range .Silences}}
+<tr>
+<td>{{.Status.State}}</td>
+<td>{{range .Matchers}}{{.}} {{end}}</td>
+<td>{{time .StartsAt}}</td><td>{{time .EndsAt}}</td>
+<td>{{.CreatedBy}}</td><td>{{.Comment}}</td>
+<td>{{if and (ne .Status.State "expired") $.Authorized (not $.ReadOnly)}}<form method="post" action="/silences/{{.ID}}/expire"><button>Expire</button></form>{{end}}</td>
+</tr>
+{{else}}
+<tr><td colspan="7">No silences</td></tr>
+{{end}}
+</table>
+
+{{if and .Authorized (not .ReadOnly)}}
+<h3>New silence</h3>
+<form method="post" action="/silences">
+<p><label>Matchers <input name="matchers" size="40" placeholder="check=web, env=~prod.*" required></label></p>
+<p><label>Duration <input name="duration" value="2h" required></label></p>
+<p><label>Created by <input name="created_by" required></label></p>
+<p><label>Comment <input name="comment" size="40"></label></p>
+<p><button>Create</button></p>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+type dashboardData struct {
+	Targets  []targetStatus
+	Silences []silence
+	Error    string
+	ReadOnly bool
+
+	// TokenSet is whether an API token is configured, without which the
+	// forms are disabled; Authorized is whether this browser has entered it.
+	TokenSet   bool
+	Authorized bool
+
+	Schedule      []ganttRow
+	ScheduleRange string
+	NowPercent    float64
+}
+
+// dashboardTokenCookie carries the API token once it has been entered on the
+// dashboard. It is SameSite=Strict, so other sites can't make a browser send
+// it along with a forged form.
+const dashboardTokenCookie = "goping_token"
+
+func dashboardAuthorized(r *http.Request, token string) bool {
+	c, err := r.Cookie(dashboardTokenCookie)
+	return err == nil && token != "" && tokenMatches(c.Value, token)
+}
+
+// sameOrigin rejects requests a browser made on behalf of another site, for
+// browsers that don't honour SameSite cookies.
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "none":
+	default:
+		return false
+	}
+	if o := r.Header.Get("Origin"); o != "" {
+		u, err := url.Parse(o)
+		return err == nil && u.Host == r.Host
+	}
+	return true
+}
+
+func registerDashboard(mux *http.ServeMux, cfg APIConfig, targets *targetSet) {
+
+	render := func(w http.ResponseWriter, r *http.Request, status int, errMsg string) {
+		data := dashboardData{
+			Silences:   silences.list(),
+			Error:      errMsg,
+			ReadOnly:   cfg.ReadOnly,
+			TokenSet:   cfg.Token != "",
+			Authorized: dashboardAuthorized(r, cfg.Token),
+		}
+		var schedule []scheduleEntry
+		for _, t := range targets.list() {
+			data.Targets = append(data.Targets, t.status())
+			schedule = append(schedule, t.scheduleStatus())
+		}
+		data.Schedule = ganttRows(schedule, timeSource.Now())
+		data.ScheduleRange = fmt.Sprintf("-%s to +%s", scheduleBefore, scheduleWindow-scheduleBefore)
+		data.NowPercent = float64(scheduleBefore) / float64(scheduleWindow) * 100
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		if err := dashboardTemplate.Execute(w, data); err != nil {
+			logger.Error("Failed to render dashboard", "error", err)
+		}
+	}
+
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		render(w, r, http.StatusOK, "")
+	})
+
+	// requireLogin guards the forms with the API token, which the browser
+	// sends as a cookie once it has been entered.
+	requireLogin := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case !sameOrigin(r):
+				render(w, r, http.StatusForbidden, "cross-origin request rejected")
+			case cfg.Token == "":
+				render(w, r, http.StatusForbidden, "disabled: no API token configured")
+			case !dashboardAuthorized(r, cfg.Token):
+				render(w, r, http.StatusUnauthorized, "enter the API token first")
+			default:
+				next(w, r)
+			}
+		}
+	}
+
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case !sameOrigin(r):
+			render(w, r, http.StatusForbidden, "cross-origin request rejected")
+			return
+		case cfg.Token == "":
+			render(w, r, http.StatusForbidden, "disabled: no API token configured")
+			return
+		case !tokenMatches(r.FormValue("token"), cfg.Token):
+			render(w, r, http.StatusUnauthorized, "invalid token")
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     dashboardTokenCookie,
+			Value:    cfg.Token,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+		})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	mux.HandleFunc("POST /logout", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: dashboardTokenCookie, Path: "/", MaxAge: -1})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	})
+
+	mux.HandleFunc("POST /targets/{name}/pause", requireLogin(func(w http.ResponseWriter, r *http.Request) {
+		t, ok := targets.get(r.PathValue("name"))
+		if !ok {
+			render(w, r, http.StatusNotFound, "unknown target")
+			return
+		}
+
+		var d time.Duration
+		if v := r.FormValue("duration"); v != "" {
+			var err error
+			if d, err = time.ParseDuration(v); err != nil || d <= 0 {
+				render(w, r, http.StatusBadRequest, "invalid duration")
+				return
+			}
+		}
+
+		t.pause(d)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}))
+
+	mux.HandleFunc("POST /targets/{name}/resume", requireLogin(func(w http.ResponseWriter, r *http.Request) {
+		t, ok := targets.get(r.PathValue("name"))
+		if !ok {
+			render(w, r, http.StatusNotFound, "unknown target")
+			return
+		}
+
+		t.resume()
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}))
+
+	mux.HandleFunc("POST /silences", requireLogin(func(w http.ResponseWriter, r *http.Request) {
+		matchers, err := parseMatchers(r.FormValue("matchers"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		duration, err := time.ParseDuration(r.FormValue("duration"))
+		if err != nil {
+			render(w, r, http.StatusBadRequest, "invalid duration: "+err.Error())
+			return
+		}
+
+		now := timeSource.Now()
+		id, err := silences.set(silence{
+			Matchers:  matchers,
+			StartsAt:  now,
+			EndsAt:    now.Add(duration),
+			CreatedBy: r.FormValue("created_by"),
+			Comment:   r.FormValue("comment"),
+		})
+		if err != nil {
+			render(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		logger.Info("Silence set", "id", id, "created_by", r.FormValue("created_by"), "comment", r.FormValue("comment"))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}))
+
+	mux.HandleFunc("POST /silences/{id}/expire", requireLogin(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if err := silences.expire(id); err != nil {
+			render(w, r, http.StatusNotFound, err.Error())
+			return
+		}
+
+		logger.Info("Silence expired", "id", id)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}))
+}
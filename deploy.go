@@ -0,0 +1,173 @@
+package goping
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var alertsDeployHeld = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goping_alerts_deploy_held_total",
+		Help: "Alerts held back because their check was in a deploy dry period",
+	},
+	[]string{"check"},
+)
+
+func init() {
+	prometheus.MustRegister(alertsDeployHeld)
+}
+
+var deploys = newDeployStore()
+
+// deploy is a dry period declared by a deployment pipeline. Checks keep
+// running and recording results, but their alerts are held back until it
+// ends. An empty Target covers every check.
+type deploy struct {
+	ID        string    `json:"id"`
+	Target    string    `json:"target,omitempty"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+
+	done chan struct{}
+}
+
+func (d *deploy) covers(check string, now time.Time) bool {
+	return (d.Target == "" || d.Target == check) && now.Before(d.EndsAt)
+}
+
+// deployStore holds the active deploys and the alerts held back by them. A
+// check that goes down during a deploy and recovers before it ends never
+// alerts; one that is still down afterwards alerts then, labeled deploy=true.
+type deployStore struct {
+	mu      sync.Mutex
+	deploys map[string]*deploy
+	held    map[string]Alert
+
+	// released are the checks whose held alert has been fired, so their
+	// recovery gets the same deploy label and resolves it.
+	released map[string]bool
+}
+
+func newDeployStore() *deployStore {
+	return &deployStore{
+		deploys:  make(map[string]*deploy),
+		held:     make(map[string]Alert),
+		released: make(map[string]bool),
+	}
+}
+
+func (s *deployStore) start(d deploy, duration time.Duration) deploy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d.ID = newSilenceID()
+	d.StartsAt = timeSource.Now()
+	d.EndsAt = d.StartsAt.Add(duration)
+	d.done = make(chan struct{})
+	s.deploys[d.ID] = &d
+	go func(id string, due <-chan time.Time, done <-chan struct{}) {
+		select {
+		case <-due:
+			s.end(id)
+		case <-done:
+		}
+	}(d.ID, timeSource.After(duration), d.done)
+	return d
+}
+
+func (s *deployStore) list() []deploy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []deploy{}
+	for _, d := range s.deploys {
+		out = append(out, *d)
+	}
+	slices.SortFunc(out, func(a, b deploy) int { return a.StartsAt.Compare(b.StartsAt) })
+	return out
+}
+
+// end finishes a deploy, early if it hasn't run out yet, and fires the held
+// alerts of checks no other deploy still covers.
+func (s *deployStore) end(id string) error {
+	s.mu.Lock()
+	d, ok := s.deploys[id]
+	if !ok {
+		s.mu.Unlock()
+		return errors.New("deploy not found")
+	}
+	close(d.done)
+	delete(s.deploys, id)
+
+	now := timeSource.Now()
+	var release []Alert
+	for check, al := range s.held {
+		if !s.coveredLocked(check, now) {
+			release = append(release, al)
+			delete(s.held, check)
+			s.released[check] = true
+		}
+	}
+	s.mu.Unlock()
+
+	logger.Info("Deploy ended", "id", id, "target", d.Target, "released_alerts", len(release))
+	for _, al := range release {
+		alerts.fire(al)
+	}
+	return nil
+}
+
+func (s *deployStore) coveredLocked(check string, now time.Time) bool {
+	for _, d := range s.deploys {
+		if d.covers(check, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns al as it should be delivered, and false if it is held back
+// for a deploy. Firing alerts are held until the deploy ends; a recovery
+// cancels the held alert, while recoveries from outages that started before
+// the deploy go through.
+func (s *deployStore) filter(al Alert, now time.Time) (Alert, bool) {
+	check := al.Labels["check"]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if al.Status == "resolved" {
+		if _, ok := s.held[check]; ok {
+			delete(s.held, check)
+			logger.Info("Held alert cancelled, check recovered during deploy", "check", check)
+			return al, false
+		}
+		if s.released[check] {
+			delete(s.released, check)
+			al.Labels = withDeployLabel(al.Labels)
+		}
+		return al, true
+	}
+
+	if s.released[check] || !s.coveredLocked(check, now) {
+		return al, true
+	}
+	al.Labels = withDeployLabel(al.Labels)
+	s.held[check] = al
+	alertsDeployHeld.WithLabelValues(check).Inc()
+	logger.Info("Alert held back during deploy", "labels", al.Labels)
+	return al, false
+}
+
+func withDeployLabel(labels map[string]string) map[string]string {
+	labels = maps.Clone(labels)
+	labels["deploy"] = "true"
+	return labels
+}
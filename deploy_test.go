@@ -0,0 +1,142 @@
+package goping
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeployFilter(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	old := timeSource
+	t.Cleanup(func() { timeSource = old })
+	timeSource = newFakeClock(start)
+
+	type step struct {
+		at        time.Duration
+		check     string
+		status    string
+		delivered bool
+		labelled  bool // with deploy="true"
+	}
+	tests := []struct {
+		name   string
+		target string
+		steps  []step
+	}{
+		{
+			name:   "other checks pass",
+			target: "web",
+			steps: []step{
+				{time.Minute, "api", "firing", true, false},
+				{2 * time.Minute, "api", "resolved", true, false},
+			},
+		},
+		{
+			name:   "recovery cancels the held alert",
+			target: "web",
+			steps: []step{
+				{time.Minute, "web", "firing", false, false},
+				{2 * time.Minute, "web", "resolved", false, false},
+			},
+		},
+		{
+			name:   "recovery from an earlier outage passes",
+			target: "web",
+			steps: []step{
+				{time.Minute, "web", "resolved", true, false},
+			},
+		},
+		{
+			name:   "every check",
+			target: "",
+			steps: []step{
+				{time.Minute, "web", "firing", false, false},
+				{time.Minute, "api", "firing", false, false},
+			},
+		},
+		{
+			name:   "after the deploy",
+			target: "web",
+			steps: []step{
+				{10 * time.Minute, "web", "firing", true, false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newDeployStore()
+			st.start(deploy{Target: tt.target}, 10*time.Minute)
+			for _, s := range tt.steps {
+				al := Alert{Labels: map[string]string{"check": s.check}, Status: s.status}
+				got, ok := st.filter(al, start.Add(s.at))
+				if ok != s.delivered {
+					t.Errorf("%s %s at %s delivered = %t, want %t", s.check, s.status, s.at, ok, s.delivered)
+				}
+				if ok && (got.Labels["deploy"] == "true") != s.labelled {
+					t.Errorf("%s %s at %s has labels %v, want deploy label %t", s.check, s.status, s.at, got.Labels, s.labelled)
+				}
+			}
+		})
+	}
+}
+
+func TestDeployEndReleasesHeldAlerts(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fake := newFakeClock(start)
+	sent := make(recordingNotifier, 10)
+
+	oldClock, oldNotifiers, oldAlerts, oldDeploys := timeSource, extraNotifiers, alerts, deploys
+	t.Cleanup(func() {
+		timeSource, extraNotifiers, alerts, deploys = oldClock, oldNotifiers, oldAlerts, oldDeploys
+	})
+	timeSource = fake
+	extraNotifiers = []Notifier{sent}
+	deploys = newDeployStore()
+
+	var err error
+	if alerts, err = newAlerter(nil, MassOutageConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var delivering sync.WaitGroup
+	for _, ch := range alerts.channels {
+		delivering.Add(1)
+		go func() {
+			defer delivering.Done()
+			ch.run(ctx)
+		}()
+	}
+	t.Cleanup(func() {
+		cancel()
+		delivering.Wait()
+	})
+
+	deploys.start(deploy{Target: "web"}, 10*time.Minute)
+	alerts.fire(Alert{Labels: map[string]string{"check": "web"}, Status: "firing"})
+	select {
+	case a := <-sent:
+		t.Fatalf("%s alert sent during the deploy", a.Status)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// The held alert goes out when the deploy runs out, and so does the
+	// recovery that follows, both marked as coming from the deploy.
+	fake.advance(10 * time.Minute)
+	receiveDeployAlert(t, sent, "firing")
+	alerts.fire(Alert{Labels: map[string]string{"check": "web"}, Status: "resolved"})
+	receiveDeployAlert(t, sent, "resolved")
+}
+
+func receiveDeployAlert(t *testing.T, sent recordingNotifier, status string) {
+	t.Helper()
+	select {
+	case a := <-sent:
+		if a.Status != status || a.Labels["deploy"] != "true" {
+			t.Errorf("sent %s alert with labels %v, want %s with deploy=\"true\"", a.Status, a.Labels, status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no %s alert sent after the deploy", status)
+	}
+}
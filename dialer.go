@@ -0,0 +1,47 @@
+package goping
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// newDialer returns a dialer for outgoing checks, bound to the configured
+// source address and/or network interface. This lets multi-homed hosts verify
+// reachability over a specific uplink.
+func newDialer(cfg CheckConfig) (*net.Dialer, error) {
+	d := &net.Dialer{}
+
+	if cfg.SourceAddress != "" {
+		ip := net.ParseIP(cfg.SourceAddress)
+		if ip == nil {
+			return nil, fmt.Errorf("check %q: invalid source_address %q", cfg.Name, cfg.SourceAddress)
+		}
+		d.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	if cfg.Interface != "" {
+		iface, err := net.InterfaceByName(cfg.Interface)
+		if err != nil {
+			return nil, fmt.Errorf("check %q: %w", cfg.Name, err)
+		}
+		if err := bindToInterface(d, iface); err != nil {
+			return nil, fmt.Errorf("check %q: %w", cfg.Name, err)
+		}
+	}
+
+	return d, nil
+}
+
+// dialerForNetwork adapts d's local address to network, since net.Dialer
+// requires a *net.UDPAddr when dialing UDP.
+func dialerForNetwork(d *net.Dialer, network string) *net.Dialer {
+	addr, ok := d.LocalAddr.(*net.TCPAddr)
+	if !ok || !strings.HasPrefix(network, "udp") {
+		return d
+	}
+
+	c := *d
+	c.LocalAddr = &net.UDPAddr{IP: addr.IP}
+	return &c
+}
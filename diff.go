@@ -0,0 +1,209 @@
+package goping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// diffChecker fetches two URLs and fails when their bodies differ, for
+// verifying that replicas, mirrors and CDN origins stay in sync.
+type diffChecker struct {
+	http        *httpChecker
+	compareURL  string
+	json        bool
+	ignorePaths [][]string
+}
+
+func newDiffChecker(cfg CheckConfig) (*diffChecker, error) {
+	if cfg.CompareURL == "" {
+		return nil, fmt.Errorf("check %q: compare_url is required", cfg.Name)
+	}
+	if len(cfg.IgnorePaths) > 0 && !cfg.JSON {
+		return nil, fmt.Errorf("check %q: ignore_paths requires json", cfg.Name)
+	}
+	if set := pairedCheckFields(cfg); len(set) > 0 {
+		return nil, fmt.Errorf("check %q: %s can't be used with diff checks", cfg.Name, strings.Join(set, ", "))
+	}
+
+	hc, err := newHTTPChecker(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &diffChecker{http: hc, compareURL: cfg.CompareURL, json: cfg.JSON}
+	for _, p := range cfg.IgnorePaths {
+		c.ignorePaths = append(c.ignorePaths, strings.Split(p, "."))
+	}
+	return c, nil
+}
+
+func (c *diffChecker) check(ctx context.Context) error {
+	var a, b httpResponse
+	var errA, errB error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		a, errA = c.http.fetch(ctx, c.http.url, true)
+	}()
+	go func() {
+		defer wg.Done()
+		b, errB = c.http.fetch(ctx, c.compareURL, true)
+	}()
+	wg.Wait()
+
+	if errA != nil {
+		return fmt.Errorf("%s: %w", c.http.url, errA)
+	}
+	if errB != nil {
+		return fmt.Errorf("%s: %w", c.compareURL, errB)
+	}
+	tr := traceFrom(ctx)
+	tr.assert("status_match", a.status == b.status)
+	if a.status != b.status {
+		return fmt.Errorf("status %d vs %d", a.status, b.status)
+	}
+	if a.size >= maxBody || b.size >= maxBody {
+		return fmt.Errorf("bodies larger than %d bytes cannot be compared", maxBody)
+	}
+
+	if c.json {
+		err := c.diffJSON(a.body, b.body)
+		tr.assert("body_match", err == nil)
+		return err
+	}
+
+	equal := bytes.Equal(a.body, b.body)
+	tr.assert("body_match", equal)
+	if !equal {
+		return fmt.Errorf("bodies differ from byte %d (%d vs %d bytes)", firstDifference(a.body, b.body), len(a.body), len(b.body))
+	}
+	return nil
+}
+
+func (c *diffChecker) diffJSON(a, b []byte) error {
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		return fmt.Errorf("%s: invalid JSON: %w", c.http.url, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return fmt.Errorf("%s: invalid JSON: %w", c.compareURL, err)
+	}
+
+	for _, p := range c.ignorePaths {
+		va = deletePath(va, p)
+		vb = deletePath(vb, p)
+	}
+
+	if path, ok := jsonDiff(va, vb, ""); !ok {
+		if path == "" {
+			path = "(root)"
+		}
+		return fmt.Errorf("JSON bodies differ at %s", path)
+	}
+	return nil
+}
+
+func firstDifference(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := range n {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// deletePath removes the value at path from v. A "*" element matches every
+// key of an object or element of an array.
+func deletePath(v any, path []string) any {
+	if len(path) == 0 {
+		return v
+	}
+	key, rest := path[0], path[1:]
+
+	switch node := v.(type) {
+	case map[string]any:
+		for k, child := range node {
+			if key != "*" && k != key {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(node, k)
+			} else {
+				node[k] = deletePath(child, rest)
+			}
+		}
+	case []any:
+		if len(rest) == 0 {
+			// Deleting array elements would shift the rest; only descend.
+			return node
+		}
+		for i, child := range node {
+			if key == "*" || key == strconv.Itoa(i) {
+				node[i] = deletePath(child, rest)
+			}
+		}
+	}
+	return v
+}
+
+// jsonDiff compares two decoded JSON values and returns the path of the
+// first difference found, in dotted form.
+func jsonDiff(a, b any, path string) (string, bool) {
+	join := func(k string) string {
+		if path == "" {
+			return k
+		}
+		return path + "." + k
+	}
+
+	switch x := a.(type) {
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok {
+			return path, false
+		}
+		keys := make([]string, 0, len(x)+len(y))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		for k := range y {
+			if _, ok := x[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			xv, xok := x[k]
+			yv, yok := y[k]
+			if xok != yok {
+				return join(k), false
+			}
+			if p, ok := jsonDiff(xv, yv, join(k)); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return path, false
+		}
+		for i := range x {
+			if p, ok := jsonDiff(x[i], y[i], join(strconv.Itoa(i))); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	default:
+		return path, reflect.DeepEqual(a, b)
+	}
+}
@@ -0,0 +1,71 @@
+package goping
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDiffCheckerRejectsSingleResponseOptions(t *testing.T) {
+	base := CheckConfig{Name: "diff-test", Type: "diff", URL: "https://origin.example.com/", CompareURL: "https://cdn.example.com/"}
+	if _, err := newDiffChecker(base); err != nil {
+		t.Fatalf("newDiffChecker: %v", err)
+	}
+
+	tests := []struct {
+		field string
+		set   func(*CheckConfig)
+	}{
+		{"fallback_urls", func(c *CheckConfig) { c.Fallbacks = []string{"https://backup.example.com/"} }},
+		{"redirect", func(c *CheckConfig) { c.Redirect = &RedirectConfig{HTTPS: true} }},
+		{"metrics", func(c *CheckConfig) { c.Metrics = []JSONMetricConfig{{Name: "a", Path: "$.a"}} }},
+		{"freshness", func(c *CheckConfig) { c.Freshness = &FreshnessConfig{MaxAge: time.Hour} }},
+		{"throughput", func(c *CheckConfig) { c.Throughput = &ThroughputConfig{MinMbps: 1} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			cfg := base
+			tt.set(&cfg)
+			_, err := newDiffChecker(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.field+" can't be used with diff checks") {
+				t.Errorf("newDiffChecker = %v, want %s rejected", err, tt.field)
+			}
+		})
+	}
+}
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		ignore  []string
+		wantErr string
+	}{
+		{name: "key order and spacing", a: `{"a": 1, "b": [1, 2]}`, b: `{"b":[1,2],"a":1}`},
+		{name: "value", a: `{"a": {"b": 1}}`, b: `{"a": {"b": 2}}`, wantErr: "differ at a.b"},
+		{name: "missing key", a: `{"a": 1}`, b: `{"a": 1, "c": 3}`, wantErr: "differ at c"},
+		{name: "array length", a: `[1, 2]`, b: `[1]`, wantErr: "differ at (root)"},
+		{name: "ignored", a: `{"at": 1, "x": 2}`, b: `{"at": 5, "x": 2}`, ignore: []string{"at"}},
+		{name: "ignored wildcard", a: `{"r": [{"n": 1, "d": 7}]}`, b: `{"r": [{"n": 1, "d": 9}]}`, ignore: []string{"r.*.d"}},
+		{name: "invalid", a: `{`, b: `{}`, wantErr: "invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newDiffChecker(CheckConfig{Name: "diff-test", Type: "diff", URL: "https://a.example.com/",
+				CompareURL: "https://b.example.com/", JSON: true, IgnorePaths: tt.ignore})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.diffJSON([]byte(tt.a), []byte(tt.b))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("diffJSON: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("diffJSON = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package goping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var defaultResolvers = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}
+
+var dnsResolverAgrees = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "goping_dns_resolver_agrees",
+		Help: "Whether a resolver returned the expected answer, or the majority answer if none is configured (1) or not (0)",
+	},
+	[]string{"check", "resolver"},
+)
+
+func init() {
+	prometheus.MustRegister(dnsResolverAgrees)
+}
+
+// dnsPropagationChecker queries the same record against several resolvers
+// and fails when their answers differ, which is what to watch right after a
+// DNS change.
+type dnsPropagationChecker struct {
+	name       string
+	record     string
+	recordType string
+	resolvers  []string
+	expected   []string
+	dialer     *net.Dialer
+}
+
+type dnsAnswer struct {
+	resolver string
+	values   []string
+	err      error
+}
+
+func (a dnsAnswer) String() string {
+	if a.err != nil {
+		return "error"
+	}
+	return strings.Join(a.values, ",")
+}
+
+func newDNSPropagationChecker(cfg CheckConfig) (*dnsPropagationChecker, error) {
+	if cfg.Record == "" {
+		return nil, fmt.Errorf("check %q: record is required", cfg.Name)
+	}
+
+	recordType := strings.ToUpper(cfg.RecordType)
+	switch recordType {
+	case "":
+		recordType = "A"
+	case "A", "AAAA", "CNAME", "MX", "NS", "TXT":
+	default:
+		return nil, fmt.Errorf("check %q: unsupported record_type %q", cfg.Name, cfg.RecordType)
+	}
+
+	resolvers := cfg.Resolvers
+	if len(resolvers) == 0 {
+		resolvers = defaultResolvers
+	}
+	resolvers = append([]string(nil), resolvers...)
+	for i, r := range resolvers {
+		if _, _, err := net.SplitHostPort(r); err != nil {
+			resolvers[i] = net.JoinHostPort(r, "53")
+		}
+	}
+
+	dialer, err := newDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dnsPropagationChecker{
+		name:       cfg.Name,
+		record:     cfg.Record,
+		recordType: recordType,
+		resolvers:  resolvers,
+		expected:   normalizeDNSValues(cfg.Expected),
+		dialer:     dialer,
+	}, nil
+}
+
+func (c *dnsPropagationChecker) check(ctx context.Context) error {
+	answers := make([]dnsAnswer, len(c.resolvers))
+
+	var wg sync.WaitGroup
+	for i, r := range c.resolvers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			values, err := c.lookup(ctx, r)
+			// The resolver reports the system nameserver it thinks it used.
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) {
+				dnsErr.Server = r
+			}
+			answers[i] = dnsAnswer{resolver: r, values: normalizeDNSValues(values), err: err}
+		}()
+	}
+	wg.Wait()
+
+	want := strings.Join(c.expected, ",")
+	if c.expected == nil {
+		want = majorityAnswer(answers)
+	}
+
+	var disagree []string
+	for _, a := range answers {
+		host, _, _ := net.SplitHostPort(a.resolver)
+		if a.err == nil && a.String() == want {
+			dnsResolverAgrees.WithLabelValues(c.name, host).Set(1)
+			continue
+		}
+		dnsResolverAgrees.WithLabelValues(c.name, host).Set(0)
+		if a.err != nil {
+			disagree = append(disagree, fmt.Sprintf("%s: %v", host, a.err))
+		} else {
+			disagree = append(disagree, fmt.Sprintf("%s: %s", host, a))
+		}
+	}
+
+	if len(disagree) > 0 {
+		return fmt.Errorf("resolvers disagree on %s %s (want %s): %s", c.recordType, c.record, want, strings.Join(disagree, "; "))
+	}
+	return nil
+}
+
+func (c *dnsPropagationChecker) lookup(ctx context.Context, server string) ([]string, error) {
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialerForNetwork(c.dialer, network).DialContext(ctx, network, server)
+		},
+	}
+
+	switch c.recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if c.recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := r.LookupIP(ctx, network, c.record)
+		values := make([]string, len(ips))
+		for i, ip := range ips {
+			values[i] = ip.String()
+		}
+		return values, err
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, c.record)
+		return []string{cname}, err
+	case "MX":
+		mxs, err := r.LookupMX(ctx, c.record)
+		values := make([]string, len(mxs))
+		for i, mx := range mxs {
+			values[i] = fmt.Sprintf("%d %s", mx.Pref, mx.Host)
+		}
+		return values, err
+	case "NS":
+		nss, err := r.LookupNS(ctx, c.record)
+		values := make([]string, len(nss))
+		for i, ns := range nss {
+			values[i] = ns.Host
+		}
+		return values, err
+	default:
+		return r.LookupTXT(ctx, c.record)
+	}
+}
+
+func normalizeDNSValues(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(v)), ".")
+	}
+	sort.Strings(out)
+	return out
+}
+
+// majorityAnswer returns the most common successful answer, preferring the
+// first resolver's answer on ties.
+func majorityAnswer(answers []dnsAnswer) string {
+	counts := make(map[string]int)
+	best, bestCount := "", 0
+	for _, a := range answers {
+		if a.err != nil {
+			continue
+		}
+		s := a.String()
+		counts[s]++
+		if counts[s] > bestCount {
+			best, bestCount = s, counts[s]
+		}
+	}
+	return best
+}
@@ -0,0 +1,209 @@
+package goping
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	defaultElasticsearchIndex         = "goping-results"
+	defaultElasticsearchBatchSize     = 500
+	defaultElasticsearchFlushInterval = 10 * time.Second
+	elasticsearchQueueSize            = 10000
+)
+
+//go:embed elasticsearch_template.json
+var elasticsearchTemplate []byte
+
+// ElasticsearchConfig configures indexing of check results into
+// Elasticsearch or OpenSearch, one document per result in daily indices.
+type ElasticsearchConfig struct {
+	URL           string        `yaml:"url"`
+	Index         string        `yaml:"index"`
+	Username      string        `yaml:"username"`
+	Password      string        `yaml:"password"`
+	APIKey        string        `yaml:"api_key"`
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+type elasticsearchSink struct {
+	cfg   ElasticsearchConfig
+	queue chan CheckResult
+}
+
+func newElasticsearchSink(cfg ElasticsearchConfig) (*elasticsearchSink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("elasticsearch: url is required")
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	if cfg.Index == "" {
+		cfg.Index = defaultElasticsearchIndex
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultElasticsearchBatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaultElasticsearchFlushInterval
+	}
+	if cfg.Password == "" {
+		cfg.Password = getEnv("ELASTICSEARCH_PASSWORD")
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = getEnv("ELASTICSEARCH_API_KEY")
+	}
+
+	return &elasticsearchSink{cfg: cfg, queue: make(chan CheckResult, elasticsearchQueueSize)}, nil
+}
+
+func (s *elasticsearchSink) write(r CheckResult) {
+	select {
+	case s.queue <- r:
+	default:
+		sinkDropped.WithLabelValues("elasticsearch").Inc()
+	}
+}
+
+// run installs the index template and then indexes queued results in bulk
+// until ctx is done, flushing what is left on the way out.
+func (s *elasticsearchSink) run(ctx context.Context) {
+	if err := s.installTemplate(ctx); err != nil {
+		logger.Error("Failed to install Elasticsearch index template", "error", err)
+	}
+
+	ticker := timeSource.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]CheckResult, 0, s.cfg.BatchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.bulk(ctx, batch); err != nil {
+			logger.Error("Failed to index check results", "count", len(batch), "error", err)
+			sinkDropped.WithLabelValues("elasticsearch").Add(float64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			for {
+				select {
+				case r := <-s.queue:
+					batch = append(batch, r)
+				default:
+					flush(shutdownCtx)
+					return
+				}
+			}
+		case r := <-s.queue:
+			batch = append(batch, r)
+			if len(batch) >= s.cfg.BatchSize {
+				flush(ctx)
+			}
+		case <-ticker.Chan():
+			flush(ctx)
+		}
+	}
+}
+
+func (s *elasticsearchSink) installTemplate(ctx context.Context) error {
+	var tmpl map[string]any
+	if err := json.Unmarshal(elasticsearchTemplate, &tmpl); err != nil {
+		return err
+	}
+	tmpl["index_patterns"] = []string{s.cfg.Index + "-*"}
+
+	body, err := json.Marshal(tmpl)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.do(ctx, http.MethodPut, "/_index_template/"+s.cfg.Index, "application/json", body)
+	return err
+}
+
+func (s *elasticsearchSink) bulk(ctx context.Context, batch []CheckResult) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range batch {
+		index := s.cfg.Index + "-" + r.Time.UTC().Format("2006.01.02")
+		enc.Encode(map[string]any{"index": map[string]string{"_index": index}})
+		enc.Encode(r)
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  any `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("failed to parse bulk response: %w", err)
+	}
+
+	failed := 0
+	if result.Errors {
+		for _, item := range result.Items {
+			for _, op := range item {
+				if op.Error != nil {
+					failed++
+					logger.Debug("Elasticsearch rejected document", "status", op.Status, "error", op.Error)
+				}
+			}
+		}
+		logger.Warn("Elasticsearch rejected some check results", "failed", failed, "total", len(batch))
+	}
+
+	sinkWritten.WithLabelValues("elasticsearch").Add(float64(len(batch) - failed))
+	sinkDropped.WithLabelValues("elasticsearch").Add(float64(failed))
+	return nil
+}
+
+func (s *elasticsearchSink) do(ctx context.Context, method, path, contentType string, body []byte) ([]byte, error) {
+	r, err := retryablehttp.NewRequestWithContext(ctx, method, s.cfg.URL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", contentType)
+	switch {
+	case s.cfg.APIKey != "":
+		r.Header.Set("Authorization", "ApiKey "+s.cfg.APIKey)
+	case s.cfg.Username != "":
+		r.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+
+	resp, err := retryClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return data, nil
+}
@@ -0,0 +1,180 @@
+package goping
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultEventsSize = 1000
+
+type EventsConfig struct {
+	Size int    `yaml:"size"`
+	File string `yaml:"file"`
+}
+
+// Event records a target changing state. From is "unknown" for the first
+// result after startup.
+type Event struct {
+	Time  time.Time `json:"time"`
+	Check string    `json:"check"`
+	Type  string    `json:"type"`
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Error string    `json:"error,omitempty"`
+}
+
+// eventLog is a fixed-size ring buffer of state transitions, optionally
+// appended to a JSON lines file so history survives restarts.
+type eventLog struct {
+	mu   sync.Mutex
+	buf  []Event
+	next int
+	full bool
+	file *os.File
+}
+
+func newEventLog(size int) *eventLog {
+	return &eventLog{buf: make([]Event, size)}
+}
+
+// openEventLog creates an event log, restoring the most recent events from
+// path if set. The file is compacted to at most size events on open.
+func openEventLog(cfg EventsConfig) (*eventLog, error) {
+	size := cfg.Size
+	if size <= 0 {
+		size = defaultEventsSize
+	}
+
+	l := newEventLog(size)
+	if cfg.File == "" {
+		return l, nil
+	}
+
+	f, err := os.Open(cfg.File)
+	if err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var e Event
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				logger.Warn("Skipping malformed event", "file", cfg.File, "error", err)
+				continue
+			}
+			l.append(e)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read events: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open events: %w", err)
+	}
+
+	tmp := cfg.File + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact events: %w", err)
+	}
+	enc := json.NewEncoder(out)
+	for _, e := range l.all() {
+		enc.Encode(e)
+	}
+	if err := out.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compact events: %w", err)
+	}
+	if err := os.Rename(tmp, cfg.File); err != nil {
+		return nil, fmt.Errorf("failed to compact events: %w", err)
+	}
+
+	l.file, err = os.OpenFile(cfg.File, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open events: %w", err)
+	}
+	return l, nil
+}
+
+func (l *eventLog) append(e Event) {
+	l.buf[l.next] = e
+	l.next = (l.next + 1) % len(l.buf)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+func (l *eventLog) record(e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.append(e)
+
+	if l.file != nil {
+		if err := json.NewEncoder(l.file).Encode(e); err != nil {
+			logger.Error("Failed to persist event", "error", err)
+		}
+	}
+}
+
+// all returns events oldest first. The caller must hold l.mu or own l.
+func (l *eventLog) all() []Event {
+	if !l.full {
+		return append([]Event(nil), l.buf[:l.next]...)
+	}
+	return append(append([]Event(nil), l.buf[l.next:]...), l.buf[:l.next]...)
+}
+
+// query returns events oldest first, filtered to the given checks (all if
+// empty) and the half-open time range [since, until). Zero times are unbounded.
+func (l *eventLog) query(checks []string, since, until time.Time) []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	want := make(map[string]bool, len(checks))
+	for _, c := range checks {
+		want[c] = true
+	}
+
+	out := []Event{}
+	for _, e := range l.all() {
+		if len(want) > 0 && !want[e.Check] {
+			continue
+		}
+		if !since.IsZero() && e.Time.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !e.Time.Before(until) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func (l *eventLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// parseTimeParam accepts either an RFC 3339 timestamp or a duration, which is
+// taken as relative to now ("12h" means twelve hours ago).
+func parseTimeParam(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return timeSource.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: expected RFC 3339 or a duration", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,148 @@
+package goping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var contentAge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "goping_check_content_age_seconds",
+		Help: "Age of the timestamp extracted from the last response of a check with a freshness block",
+	},
+	[]string{"check"},
+)
+
+func init() {
+	prometheus.MustRegister(contentAge)
+}
+
+// FreshnessConfig fails an HTTP check when the timestamp in a response header
+// or at a JSONPath in the body is older than MaxAge. Without either, the
+// Last-Modified header is used.
+type FreshnessConfig struct {
+	Header string        `yaml:"header,omitempty"`
+	Path   string        `yaml:"path,omitempty"`
+	Format string        `yaml:"format,omitempty"`
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+type freshnessChecker struct {
+	name string
+	cfg  FreshnessConfig
+	path []string
+}
+
+func newFreshnessChecker(cfg CheckConfig) (*freshnessChecker, error) {
+	f := cfg.Freshness
+	if f.MaxAge <= 0 {
+		return nil, fmt.Errorf("check %q: freshness: max_age is required", cfg.Name)
+	}
+	if f.Header != "" && f.Path != "" {
+		return nil, fmt.Errorf("check %q: freshness: header and path are mutually exclusive", cfg.Name)
+	}
+
+	c := &freshnessChecker{name: cfg.Name, cfg: *f}
+	if f.Path != "" {
+		path, err := parseJSONPath(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("check %q: freshness: %w", cfg.Name, err)
+		}
+		c.path = path
+	} else if c.cfg.Header == "" {
+		c.cfg.Header = "Last-Modified"
+	}
+	return c, nil
+}
+
+// needsBody reports whether the timestamp comes from the response body.
+func (c *freshnessChecker) needsBody() bool {
+	return c.path != nil
+}
+
+func (c *freshnessChecker) check(ctx context.Context, header http.Header, body []byte) error {
+	ts, err := c.extract(header, body)
+	if err != nil {
+		contentAge.DeleteLabelValues(c.name)
+		traceFrom(ctx).assert("freshness", false)
+		return fmt.Errorf("freshness: %w", err)
+	}
+
+	age := timeSource.Now().Sub(ts)
+	contentAge.WithLabelValues(c.name).Set(age.Seconds())
+
+	ok := age <= c.cfg.MaxAge
+	traceFrom(ctx).assert("freshness", ok)
+	if !ok {
+		return fmt.Errorf("content is %s old, older than %s", age.Round(time.Second), c.cfg.MaxAge)
+	}
+	return nil
+}
+
+func (c *freshnessChecker) extract(header http.Header, body []byte) (time.Time, error) {
+	if c.path == nil {
+		v := header.Get(c.cfg.Header)
+		if v == "" {
+			return time.Time{}, fmt.Errorf("no %s header", c.cfg.Header)
+		}
+		return parseTimestamp(v, c.cfg.Format)
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return time.Time{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	v, ok := lookupPath(doc, c.path)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no value at %s", c.cfg.Path)
+	}
+	switch v := v.(type) {
+	case string:
+		return parseTimestamp(v, c.cfg.Format)
+	case float64:
+		return parseTimestamp(strconv.FormatFloat(v, 'f', -1, 64), c.cfg.Format)
+	}
+	return time.Time{}, fmt.Errorf("value at %s is not a timestamp", c.cfg.Path)
+}
+
+// parseTimestamp parses s with format, which is unix, unix_ms or a Go time
+// layout. Without a format, RFC 3339, HTTP dates and Unix seconds are tried.
+func parseTimestamp(s, format string) (time.Time, error) {
+	switch format {
+	case "unix", "unix_ms":
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s timestamp %q", format, s)
+		}
+		if format == "unix_ms" {
+			f /= 1e3
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case "":
+	default:
+		t, err := time.Parse(format, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q: %w", s, err)
+		}
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return t, nil
+	}
+	if t, err := parseTimestamp(s, "unix"); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q, set format", s)
+}
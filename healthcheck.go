@@ -0,0 +1,40 @@
+package goping
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// runHealthcheck probes /readyz of a local goping and exits 0 if it is ready,
+// so container images don't need curl or wget for their health checks.
+func runHealthcheck(args []string) int {
+	fs := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	port := fs.String("metrics-port", "8080", "port goping is listening on")
+	url := fs.String("url", "", "base URL of the goping to check (default http://localhost:<metrics-port>)")
+	timeout := fs.Duration("timeout", 5*time.Second, "how long to wait for a response")
+	fs.Parse(args)
+
+	if *url == "" {
+		*url = "http://localhost:" + *port
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	resp, err := client.Get(strings.TrimSuffix(*url, "/") + "/readyz")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		fmt.Fprintf(os.Stderr, "not healthy: %s: %s\n", resp.Status, strings.TrimSpace(string(body)))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,127 @@
+package goping
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultHeartbeatInterval = 1 * time.Minute
+
+var (
+	watchdog = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "goping_watchdog",
+			Help: "Always 1 while every check scheduler is running on time; alert on its absence or on 0",
+		},
+	)
+
+	heartbeatLastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "goping_heartbeat_last_success_timestamp_seconds",
+			Help: "Unix time of the last scheduler cycle in which every check ran on time",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(watchdog)
+	prometheus.MustRegister(heartbeatLastSuccess)
+}
+
+type HeartbeatConfig struct {
+	URL      string        `yaml:"url"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// stalled reports whether the target has missed its schedule, which means
+// either its goroutine has died or a check is stuck past its timeout. Paused
+// targets are never stalled.
+func (t *target) stalled(now, started time.Time) bool {
+	if t.isPaused(now) {
+		return false
+	}
+
+	t.mu.Lock()
+	last := t.lastRun
+	if t.resumedAt.After(last) {
+		last = t.resumedAt
+	}
+	t.mu.Unlock()
+
+	if last.IsZero() {
+		last = started
+	}
+	return now.Sub(last) > 2*t.cfg.Interval+t.cfg.Deadline
+}
+
+// startHeartbeat checks every interval that all schedulers are healthy and, if
+// so, pings the configured dead-man's-switch URL. When goping dies or wedges
+// the pings stop, and the external service raises the alarm.
+func startHeartbeat(ctx context.Context, cfg HeartbeatConfig, targets *targetSet) {
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+	}
+
+	started := timeSource.Now()
+	ticker := timeSource.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.Chan():
+			if clock.settling(now) {
+				// Schedulers may not have caught up with a resume yet.
+				logger.Debug("Skipping heartbeat after clock jump")
+				continue
+			}
+
+			var stalled []string
+			for _, t := range targets.list() {
+				if t.stalled(now, started) {
+					stalled = append(stalled, t.cfg.Name)
+				}
+			}
+
+			if len(stalled) > 0 {
+				logger.Error("Checks are not running on schedule, skipping heartbeat", "checks", stalled)
+				watchdog.Set(0)
+				continue
+			}
+
+			watchdog.Set(1)
+			heartbeatLastSuccess.SetToCurrentTime()
+
+			if cfg.URL != "" {
+				sendHeartbeat(ctx, cfg.URL)
+			}
+		}
+	}
+}
+
+func sendHeartbeat(ctx context.Context, url string) {
+	r, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		logger.Error("Failed to create heartbeat request", "error", err)
+		return
+	}
+
+	resp, err := retryClient.Do(r)
+	if err != nil {
+		logger.Error("Failed to send heartbeat", "error", err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		logger.Warn("Heartbeat returned non-success status", "status_code", resp.StatusCode)
+		return
+	}
+	logger.Debug("Heartbeat sent", "status_code", resp.StatusCode)
+}
@@ -0,0 +1,344 @@
+package goping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultHistoryDays = 90
+
+	// hourlyRetention bounds the hourly rollups; longer ranges use daily ones.
+	hourlyRetention = 7 * 24 * time.Hour
+
+	historySaveInterval = time.Minute
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the latency histogram
+// kept in each rollup. Slower runs go in one more bucket past the last.
+var latencyBuckets = prometheus.DefBuckets
+
+var history = newHistory(defaultHistoryDays)
+
+type HistoryConfig struct {
+	Days int    `yaml:"days"`
+	File string `yaml:"file"`
+}
+
+// rollup aggregates the runs of one check over an hour or a UTC day, so
+// availability over long ranges is a sum over a few hundred buckets at most.
+// Latency counts successful runs per latencyBuckets bucket.
+type rollup struct {
+	Start    time.Time `json:"start"`
+	Runs     int       `json:"runs"`
+	Up       int       `json:"up"`
+	Duration float64   `json:"durationSeconds"`
+	Latency  []int     `json:"latency,omitempty"`
+}
+
+// historyPoint is a rollup as served by the API.
+type historyPoint struct {
+	Start        time.Time `json:"start"`
+	Runs         int       `json:"runs"`
+	Availability float64   `json:"availability"`
+	AvgDuration  float64   `json:"avgDurationSeconds"`
+}
+
+type checkHistory struct {
+	Check        string         `json:"check"`
+	Availability *float64       `json:"availability"`
+	Points       []historyPoint `json:"points"`
+}
+
+// heatmapPoint holds the latency histogram of one rollup; Counts has one
+// entry per bound in checkHeatmap.Buckets plus one for slower runs.
+type heatmapPoint struct {
+	Start  time.Time `json:"start"`
+	Counts []int     `json:"counts"`
+}
+
+type checkHeatmap struct {
+	Check   string         `json:"check"`
+	Buckets []float64      `json:"bucketsSeconds"`
+	Points  []heatmapPoint `json:"points"`
+}
+
+// historyStore keeps hourly and daily availability rollups per check,
+// optionally saved to a JSON file so history survives restarts. The rollups
+// are kept apart from Storage: they are small, a few hundred buckets per
+// check, are updated on every run rather than appended to, and work the same
+// with every storage backend, registered ones included.
+type historyStore struct {
+	mu     sync.Mutex
+	days   int
+	hourly map[string][]rollup
+	daily  map[string][]rollup
+
+	file  string
+	dirty bool
+}
+
+type historyFile struct {
+	Hourly map[string][]rollup `json:"hourly"`
+	Daily  map[string][]rollup `json:"daily"`
+}
+
+func newHistory(days int) *historyStore {
+	return &historyStore{
+		days:   days,
+		hourly: make(map[string][]rollup),
+		daily:  make(map[string][]rollup),
+	}
+}
+
+// openHistory creates a history store, loading saved rollups from the
+// configured file if it exists.
+func openHistory(cfg HistoryConfig) (*historyStore, error) {
+	days := cfg.Days
+	if days <= 0 {
+		days = defaultHistoryDays
+	}
+
+	h := newHistory(days)
+	h.file = cfg.File
+	if h.file == "" {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(h.file)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var f historyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse history: %w", err)
+	}
+	if f.Hourly != nil {
+		h.hourly = f.Hourly
+	}
+	if f.Daily != nil {
+		h.daily = f.Daily
+	}
+	return h, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// addToRollups counts r into the bucket starting at start, adding the bucket
+// in order if needed, and drops buckets older than cutoff. Results can
+// arrive out of order, from on-demand runs or after the clock is set back,
+// so the bucket isn't necessarily the last.
+func addToRollups(buckets []rollup, start time.Time, r CheckResult, cutoff time.Time) []rollup {
+	i, found := slices.BinarySearchFunc(buckets, start, func(b rollup, t time.Time) int {
+		return b.Start.Compare(t)
+	})
+	if !found {
+		buckets = slices.Insert(buckets, i, rollup{Start: start})
+	}
+	b := &buckets[i]
+	b.Runs++
+	if r.Up {
+		b.Up++
+		if b.Latency == nil {
+			b.Latency = make([]int, len(latencyBuckets)+1)
+		}
+		b.Latency[sort.SearchFloat64s(latencyBuckets, r.Duration)]++
+	}
+	b.Duration += r.Duration
+
+	i = 0
+	for i < len(buckets) && buckets[i].Start.Before(cutoff) {
+		i++
+	}
+	return buckets[i:]
+}
+
+func (h *historyStore) record(r CheckResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t := r.Time.UTC()
+	h.hourly[r.Check] = addToRollups(h.hourly[r.Check], t.Truncate(time.Hour), r, t.Add(-hourlyRetention))
+	h.daily[r.Check] = addToRollups(h.daily[r.Check], startOfDay(t), r, startOfDay(t).AddDate(0, 0, -h.days))
+	h.dirty = true
+}
+
+// rollups returns the rollups for a resolution ("hour" or "day", the default)
+// and their width. The caller must hold h.mu.
+func (h *historyStore) rollups(resolution string) (map[string][]rollup, time.Duration, error) {
+	switch resolution {
+	case "hour":
+		return h.hourly, time.Hour, nil
+	case "day", "":
+		return h.daily, 24 * time.Hour, nil
+	default:
+		return nil, 0, fmt.Errorf("invalid resolution %q: expected hour or day", resolution)
+	}
+}
+
+// inRange reports whether a bucket of the given width starting at start
+// overlaps [since, until). Zero times are unbounded.
+func inRange(start time.Time, width time.Duration, since, until time.Time) bool {
+	if !since.IsZero() && !start.Add(width).After(since) {
+		return false
+	}
+	return until.IsZero() || start.Before(until)
+}
+
+func checkNames(source map[string][]rollup) []string {
+	var checks []string
+	for check := range source {
+		checks = append(checks, check)
+	}
+	sort.Strings(checks)
+	return checks
+}
+
+// query returns the rollups of each check at the given resolution
+// overlapping [since, until), with the availability over all of them.
+func (h *historyStore) query(checks []string, resolution string, since, until time.Time) ([]checkHistory, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	source, width, err := h.rollups(resolution)
+	if err != nil {
+		return nil, err
+	}
+	if len(checks) == 0 {
+		checks = checkNames(source)
+	}
+
+	out := make([]checkHistory, 0, len(checks))
+	for _, check := range checks {
+		ch := checkHistory{Check: check, Points: []historyPoint{}}
+		var runs, up int
+		for _, b := range source[check] {
+			if !inRange(b.Start, width, since, until) {
+				continue
+			}
+			runs += b.Runs
+			up += b.Up
+			ch.Points = append(ch.Points, historyPoint{
+				Start:        b.Start,
+				Runs:         b.Runs,
+				Availability: float64(b.Up) / float64(b.Runs),
+				AvgDuration:  b.Duration / float64(b.Runs),
+			})
+		}
+		if runs > 0 {
+			a := float64(up) / float64(runs)
+			ch.Availability = &a
+		}
+		out = append(out, ch)
+	}
+	return out, nil
+}
+
+// heatmap returns the latency histograms of each check's rollups like query.
+// Rollups without successful runs, or saved before histograms were kept, are
+// left out.
+func (h *historyStore) heatmap(checks []string, resolution string, since, until time.Time) ([]checkHeatmap, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	source, width, err := h.rollups(resolution)
+	if err != nil {
+		return nil, err
+	}
+	if len(checks) == 0 {
+		checks = checkNames(source)
+	}
+
+	out := make([]checkHeatmap, 0, len(checks))
+	for _, check := range checks {
+		ch := checkHeatmap{Check: check, Buckets: latencyBuckets, Points: []heatmapPoint{}}
+		for _, b := range source[check] {
+			if b.Latency == nil || !inRange(b.Start, width, since, until) {
+				continue
+			}
+			ch.Points = append(ch.Points, heatmapPoint{Start: b.Start, Counts: slices.Clone(b.Latency)})
+		}
+		out = append(out, ch)
+	}
+	return out, nil
+}
+
+// availability returns the share of successful runs of check over the
+// retained daily history, and false if it has never run.
+func (h *historyStore) availability(check string) (float64, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var runs, up int
+	for _, b := range h.daily[check] {
+		runs += b.Runs
+		up += b.Up
+	}
+	if runs == 0 {
+		return 0, false
+	}
+	return float64(up) / float64(runs), true
+}
+
+func (h *historyStore) save() error {
+	h.mu.Lock()
+	if h.file == "" || !h.dirty {
+		h.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(historyFile{Hourly: h.hourly, Daily: h.daily})
+	h.dirty = false
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := h.file + ".tmp"
+	err = os.WriteFile(tmp, data, 0o644)
+	if err == nil {
+		err = os.Rename(tmp, h.file)
+	}
+	if err != nil {
+		// Try again on the next save.
+		h.mu.Lock()
+		h.dirty = true
+		h.mu.Unlock()
+	}
+	return err
+}
+
+// run saves the rollups periodically until ctx is done.
+func (h *historyStore) run(ctx context.Context) {
+	ticker := timeSource.NewTicker(historySaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+			if err := h.save(); err != nil {
+				logger.Error("Failed to save history", "error", err)
+			}
+		}
+	}
+}
+
+func (h *historyStore) close() error {
+	return h.save()
+}
@@ -0,0 +1,93 @@
+package goping
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryOutOfOrder(t *testing.T) {
+	h := newHistory(90)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour int, up bool) CheckResult {
+		return CheckResult{Time: day.Add(time.Duration(hour)*time.Hour + 30*time.Minute), Check: "api", Up: up, Duration: 0.1}
+	}
+
+	// A late result for an earlier hour, and one for the previous day, must
+	// be counted in their own buckets, not the latest.
+	for _, r := range []CheckResult{at(2, true), at(5, true), at(2, false), at(3, true), at(-1, false)} {
+		h.record(r)
+	}
+
+	got, err := h.query([]string{"api"}, "hour", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []historyPoint{
+		{Start: day.Add(-time.Hour), Runs: 1, Availability: 0},
+		{Start: day.Add(2 * time.Hour), Runs: 2, Availability: 0.5},
+		{Start: day.Add(3 * time.Hour), Runs: 1, Availability: 1},
+		{Start: day.Add(5 * time.Hour), Runs: 1, Availability: 1},
+	}
+	points := got[0].Points
+	if len(points) != len(want) {
+		t.Fatalf("hourly points %+v, want %+v", points, want)
+	}
+	for i, p := range points {
+		if !p.Start.Equal(want[i].Start) || p.Runs != want[i].Runs || p.Availability != want[i].Availability {
+			t.Errorf("point %d = %+v, want %+v", i, p, want[i])
+		}
+	}
+
+	daily, err := h.query([]string{"api"}, "day", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(daily[0].Points); n != 2 || daily[0].Points[1].Runs != 4 {
+		t.Errorf("daily points %+v, want the previous day and 4 runs today", daily[0].Points)
+	}
+	if a, ok := h.availability("api"); !ok || a != 0.6 {
+		t.Errorf("availability = %v, %t, want 0.6", a, ok)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	h := newHistory(2)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, d := range []time.Duration{0, 24 * time.Hour, 9 * 24 * time.Hour} {
+		h.record(CheckResult{Time: start.Add(d), Check: "api", Up: true})
+	}
+
+	hourly, _ := h.query(nil, "hour", time.Time{}, time.Time{})
+	if n := len(hourly[0].Points); n != 1 {
+		t.Errorf("%d hourly points, want only the last within 7 days", n)
+	}
+	daily, _ := h.query(nil, "day", time.Time{}, time.Time{})
+	if n := len(daily[0].Points); n != 1 {
+		t.Errorf("%d daily points, want only the last within 2 days", n)
+	}
+}
+
+func TestHistorySaveAndLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.json")
+	h, err := openHistory(HistoryConfig{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.record(CheckResult{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Check: "api", Up: true, Duration: 0.2})
+	if err := h.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := openHistory(HistoryConfig{File: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := loaded.availability("api"); !ok || a != 1 {
+		t.Errorf("loaded availability = %v, %t, want 1", a, ok)
+	}
+	heat, _ := loaded.heatmap([]string{"api"}, "hour", time.Time{}, time.Time{})
+	if len(heat[0].Points) != 1 {
+		t.Errorf("loaded heatmap %+v, want the saved histogram", heat)
+	}
+}
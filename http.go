@@ -0,0 +1,361 @@
+package goping
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	throughputMbps = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goping_check_throughput_mbps",
+			Help: "Download throughput achieved by the last run of a check, in megabits per second",
+		},
+		[]string{"check"},
+	)
+
+	fallbackActive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goping_check_fallback_active",
+			Help: "Whether the last successful run of a check was answered by a fallback URL rather than the primary",
+		},
+		[]string{"check", "url"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(throughputMbps)
+	prometheus.MustRegister(fallbackActive)
+}
+
+// maxBody caps how much of a response is held in memory for inspection.
+const maxBody = 10 << 20
+
+// ThroughputConfig turns an HTTP check into a download test of a file of
+// known size, so degraded links show up and not just broken ones.
+type ThroughputConfig struct {
+	ExpectedBytes int64   `yaml:"expected_bytes,omitempty"`
+	MinMbps       float64 `yaml:"min_mbps,omitempty"`
+}
+
+type httpChecker struct {
+	name       string
+	url        string
+	fallbacks  []string
+	serving    string
+	method     string
+	client     *http.Client
+	resolver   *cachingResolver
+	throughput *ThroughputConfig
+	metrics    []jsonMetric
+	redirect   *redirectAsserter
+	freshness  *freshnessChecker
+
+	// tls is the connection of the primary URL's last response.
+	tls tlsRecorder
+}
+
+func newHTTPChecker(cfg CheckConfig) (*httpChecker, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("check %q: url is required", cfg.Name)
+	}
+
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	if cfg.Throughput != nil && len(cfg.Metrics) > 0 {
+		return nil, fmt.Errorf("check %q: metrics can't be combined with throughput", cfg.Name)
+	}
+	metrics, err := newJSONMetrics(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer, err := newDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+
+	var resolver *cachingResolver
+	if cfg.ResolveInterval > 0 {
+		resolver = newCachingResolver(cfg.Name, cfg.ResolveInterval, dialer)
+		resolver.onChange = transport.CloseIdleConnections
+		transport.DialContext = resolver.DialContext
+	}
+
+	var rt http.RoundTripper = transport
+	if wrapTransport != nil {
+		rt = wrapTransport(cfg.Name, transport)
+	}
+
+	c := &httpChecker{
+		name:       cfg.Name,
+		url:        cfg.URL,
+		fallbacks:  cfg.Fallbacks,
+		method:     method,
+		client:     &http.Client{Transport: redirectLimiter{rt}},
+		resolver:   resolver,
+		throughput: cfg.Throughput,
+		metrics:    metrics,
+	}
+	for _, u := range c.fallbacks {
+		fallbackActive.WithLabelValues(cfg.Name, u).Set(0)
+	}
+	if cfg.Redirect != nil {
+		// Fallbacks are other URLs, which can't be expected to redirect to
+		// the same place.
+		if len(cfg.Fallbacks) > 0 && (cfg.Redirect.Host != "" || cfg.Redirect.Chain != nil) {
+			return nil, fmt.Errorf("check %q: redirect host and chain can't be combined with fallback_urls", cfg.Name)
+		}
+		c.redirect = newRedirectAsserter(cfg.Name, *cfg.Redirect)
+	}
+	if cfg.Freshness != nil {
+		c.freshness, err = newFreshnessChecker(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if c.throughput != nil && c.freshness.needsBody() {
+			return nil, fmt.Errorf("check %q: freshness path can't be combined with throughput", cfg.Name)
+		}
+	}
+	return c, nil
+}
+
+// pairedCheckFields returns the http options cfg sets that canary and diff
+// checks, which compare two responses of their own, don't support.
+func pairedCheckFields(cfg CheckConfig) []string {
+	var set []string
+	if len(cfg.Fallbacks) > 0 {
+		set = append(set, "fallback_urls")
+	}
+	if cfg.Redirect != nil {
+		set = append(set, "redirect")
+	}
+	if len(cfg.Metrics) > 0 {
+		set = append(set, "metrics")
+	}
+	if cfg.Freshness != nil {
+		set = append(set, "freshness")
+	}
+	if cfg.Throughput != nil {
+		set = append(set, "throughput")
+	}
+	return set
+}
+
+// httpResponse summarises a fetched response.
+type httpResponse struct {
+	status int
+	header http.Header
+	tls    *tls.ConnectionState
+	size   int64
+	body   []byte
+
+	// chain lists the URLs requested, starting with the original one and
+	// ending with the one that returned the response.
+	chain []string
+
+	// latency runs from sending the request until the body is read; bodyTime
+	// covers only reading the body.
+	latency  time.Duration
+	bodyTime time.Duration
+}
+
+// fetch requests url, keeping up to maxBody bytes of the body if keepBody is
+// set and discarding it otherwise.
+func (c *httpChecker) fetch(ctx context.Context, url string, keepBody bool) (httpResponse, error) {
+	var res httpResponse
+
+	req, err := http.NewRequestWithContext(ctx, c.method, url, nil)
+	if err != nil {
+		return res, err
+	}
+
+	// Wait before starting the clock so latency isn't skewed by the limit.
+	// Redirects wait in redirectLimiter.
+	if err := hostLimits.wait(ctx, req.URL.Hostname()); err != nil {
+		return res, err
+	}
+
+	// Resolve on every run, not only when dialing, so address changes are
+	// seen even while a kept-alive connection is reused.
+	if c.resolver != nil {
+		if _, err := netip.ParseAddr(req.URL.Hostname()); err != nil {
+			if _, err := c.resolver.resolve(ctx, req.URL.Hostname()); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	start := time.Now()
+	tr := traceFrom(ctx)
+	if tr != nil {
+		req = req.WithContext(httptrace.WithClientTrace(ctx, tr.httpClientTrace(start)))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+
+	bodyStart := time.Now()
+	if keepBody {
+		res.body, err = io.ReadAll(io.LimitReader(resp.Body, maxBody))
+		res.size = int64(len(res.body))
+	} else {
+		res.size, err = io.Copy(io.Discard, resp.Body)
+	}
+	res.bodyTime = time.Since(bodyStart)
+	res.latency = time.Since(start)
+	res.status = resp.StatusCode
+	res.header = resp.Header
+	res.tls = resp.TLS
+	res.chain = redirectChain(resp)
+	tr.phase("transfer", res.bodyTime)
+	tr.addBytes(res.size)
+	if err != nil {
+		ce := classifyCheckError(fmt.Errorf("failed to read body: %w", err))
+		ce.Phase = "transfer"
+		return res, ce
+	}
+	return res, nil
+}
+
+// check tries the primary URL and then each fallback in order, passing if
+// any of them does.
+func (c *httpChecker) check(ctx context.Context) error {
+	err := c.checkURL(ctx, c.url)
+	if err == nil || len(c.fallbacks) == 0 {
+		c.setServing("")
+		return err
+	}
+
+	errs := []string{fmt.Sprintf("%s: %v", c.url, err)}
+	for _, u := range c.fallbacks {
+		if ctx.Err() != nil {
+			break
+		}
+		ferr := c.checkURL(ctx, u)
+		if ferr == nil {
+			c.setServing(u)
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", u, ferr))
+	}
+	return fmt.Errorf("all URLs failed: %s", strings.Join(errs, "; "))
+}
+
+// setServing records which fallback answered, or none for the primary. Runs
+// of a check don't overlap, so serving needs no lock.
+func (c *httpChecker) setServing(url string) {
+	if len(c.fallbacks) == 0 || url == c.serving {
+		return
+	}
+	if url == "" {
+		logger.Info("Check answered by primary URL again", "check", c.name, "url", c.url)
+	} else {
+		logger.Warn("Check answered by fallback URL", "check", c.name, "url", url)
+	}
+	c.serving = url
+
+	for _, u := range c.fallbacks {
+		v := 0.0
+		if u == url {
+			v = 1
+		}
+		fallbackActive.WithLabelValues(c.name, u).Set(v)
+	}
+}
+
+func (c *httpChecker) checkURL(ctx context.Context, url string) error {
+	keepBody := len(c.metrics) > 0 || c.freshness != nil && c.freshness.needsBody()
+	res, err := c.fetch(ctx, url, keepBody)
+	if err != nil {
+		return err
+	}
+	if url == c.url {
+		c.tls.record(res.tls)
+	}
+
+	ok := res.status < 400
+	traceFrom(ctx).assert("status", ok)
+	if !ok {
+		return statusError(res.status, fmt.Errorf("unexpected status code %d", res.status))
+	}
+
+	if c.redirect != nil {
+		if err := c.redirect.check(ctx, res.chain); err != nil {
+			return assertionError(err, false)
+		}
+	}
+
+	if len(c.metrics) > 0 {
+		exportJSONMetrics(c.name, c.metrics, res.body)
+	}
+
+	if c.freshness != nil {
+		if err := c.freshness.check(ctx, res.header, res.body); err != nil {
+			return assertionError(err, false)
+		}
+	}
+
+	// A slow or short download may well be transient, so unlike the other
+	// assertions it is retried.
+	if c.throughput != nil {
+		if err := c.checkThroughput(ctx, res.size, res.bodyTime); err != nil {
+			return assertionError(err, true)
+		}
+	}
+	return nil
+}
+
+// checkThroughput measures the body transfer only, excluding DNS, connect and
+// time to first byte, which are latency rather than bandwidth.
+func (c *httpChecker) checkThroughput(ctx context.Context, n int64, elapsed time.Duration) error {
+	tr := traceFrom(ctx)
+
+	if want := c.throughput.ExpectedBytes; want > 0 {
+		tr.assert("expected_bytes", n == want)
+		if n != want {
+			return fmt.Errorf("downloaded %d bytes, expected %d", n, want)
+		}
+	}
+
+	// A body read faster than the clock's resolution has no measurable
+	// rate; don't report one, or compare +Inf or NaN with the minimum.
+	if elapsed <= 0 {
+		logger.Debug("Transfer too fast to measure throughput", "check", c.name, "bytes", n)
+		return nil
+	}
+
+	mbps := float64(n*8) / elapsed.Seconds() / 1e6
+	throughputMbps.WithLabelValues(c.name).Set(mbps)
+	logger.Debug("Measured throughput", "check", c.name, "bytes", n, "elapsed", elapsed, "mbps", mbps)
+
+	if want := c.throughput.MinMbps; want > 0 {
+		tr.assert("min_mbps", mbps >= want)
+		if mbps < want {
+			return fmt.Errorf("throughput %.1f Mbps below minimum %.1f Mbps", mbps, want)
+		}
+	}
+	return nil
+}
+
+func (c *httpChecker) lastTLS() *tlsInfo {
+	return c.tls.lastTLS()
+}
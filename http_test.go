@@ -0,0 +1,70 @@
+package goping
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHTTPFallbacks(t *testing.T) {
+	server := func(up *atomic.Bool) *httptest.Server {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !up.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		t.Cleanup(s.Close)
+		return s
+	}
+	var primaryUp, firstUp, secondUp atomic.Bool
+	primary, first, second := server(&primaryUp), server(&firstUp), server(&secondUp)
+
+	const check = "fallback-test"
+	t.Cleanup(func() { fallbackActive.DeletePartialMatch(map[string]string{"check": check}) })
+	c, err := newHTTPChecker(CheckConfig{Name: check, Type: "http", URL: primary.URL, Fallbacks: []string{first.URL, second.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	active := func() (float64, float64) {
+		return testutil.ToFloat64(fallbackActive.WithLabelValues(check, first.URL)),
+			testutil.ToFloat64(fallbackActive.WithLabelValues(check, second.URL))
+	}
+	tests := []struct {
+		name                   string
+		primary, first, second bool
+		wantErr                bool
+		wantFirst, wantSecond  float64
+	}{
+		{name: "primary", primary: true, first: true, second: true},
+		{name: "first fallback", first: true, second: true, wantFirst: 1},
+		{name: "second fallback", second: true, wantSecond: 1},
+		// A failed run leaves the last fallback that answered marked.
+		{name: "all down", wantErr: true, wantSecond: 1},
+		{name: "primary again", primary: true},
+	}
+	for _, tt := range tests {
+		primaryUp.Store(tt.primary)
+		firstUp.Store(tt.first)
+		secondUp.Store(tt.second)
+
+		err := c.check(ctx)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: check = %v, want an error %t", tt.name, err, tt.wantErr)
+		}
+		if err != nil && strings.Count(err.Error(), "127.0.0.1") != 3 {
+			t.Errorf("%s: error %q doesn't name every URL", tt.name, err)
+		}
+		if f, s := active(); f != tt.wantFirst || s != tt.wantSecond {
+			t.Errorf("%s: fallback active = %v, %v, want %v, %v", tt.name, f, s, tt.wantFirst, tt.wantSecond)
+		}
+	}
+}
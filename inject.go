@@ -0,0 +1,88 @@
+package goping
+
+import (
+	"net/http"
+	"time"
+)
+
+// The variables in this file are injection points for simulating failures,
+// used by goping's tests and set by programs that embed goping through
+// SetClock, SetTransportWrapper and AddNotifier. They are set before Main or
+// Probe is called and left alone while checks run.
+
+// Clock is the time source for scheduling: when checks, heartbeats, the
+// keep-alive ping, maintenance calendar syncs, history saves and storage
+// pruning run, how long retries back off and runs take, when pauses,
+// silences, deploys and cached DNS answers run out, when rate-limited
+// notifiers and mass outages release alerts, how old content and
+// certificates are, and the times stamped on results, events, alerts and
+// snapshots. A fake clock lets a schedule be stepped through
+// deterministically. Per-attempt timeouts and deadlines are context
+// deadlines, and stay in real time, as do the phase timings of a trace and
+// the detection of clock jumps.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker is the part of time.Ticker the scheduler uses.
+type Ticker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+var timeSource Clock = realClock{}
+
+// SetClock makes c the time source for scheduling, in place of the real
+// clock.
+func SetClock(c Clock) {
+	timeSource = c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct{ *time.Ticker }
+
+func (t realTicker) Chan() <-chan time.Time { return t.C }
+
+// wrapTransport, if set, is given the transport of every HTTP check as it
+// is created and returns the one the check uses. Returning a RoundTripper
+// that fails, stalls or answers from memory simulates a target without
+// touching the network; wrapping rt keeps the real requests.
+var wrapTransport func(check string, rt http.RoundTripper) http.RoundTripper
+
+// SetTransportWrapper has wrap choose the transport of every HTTP check, and
+// of goping's own requests, Ping included, under an empty check name.
+func SetTransportWrapper(wrap func(check string, rt http.RoundTripper) http.RoundTripper) {
+	wrapTransport = wrap
+	wrapRetryClient()
+}
+
+// retryTransport is retryClient's own transport, before any wrapping.
+var retryTransport = retryClient.HTTPClient.Transport
+
+// wrapRetryClient applies wrapTransport to retryClient, which makes goping's
+// own requests: the keep-alive ping, heartbeats, maintenance calendars and
+// Elasticsearch. They are wrapped under an empty check name. It is called at
+// startup and whenever the wrapper changes.
+func wrapRetryClient() {
+	rt := retryTransport
+	if wrapTransport != nil {
+		rt = wrapTransport("", rt)
+	}
+	retryClient.HTTPClient.Transport = rt
+}
+
+// extraNotifiers receive every alert alongside the configured notifiers,
+// each from its own queue, without a rate limit.
+var extraNotifiers []Notifier
+
+// AddNotifier has n receive every alert alongside the configured notifiers.
+func AddNotifier(n Notifier) {
+	extraNotifiers = append(extraNotifiers, n)
+}
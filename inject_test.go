@@ -0,0 +1,303 @@
+package goping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock only moves when advance is called, firing the tickers and
+// timers that come due on the way.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+type fakeWaiter struct {
+	at     time.Time
+	period time.Duration // zero for After
+	c      chan time.Time
+	stop   bool
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return c.add(d, 0).c
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	return fakeTicker{c, c.add(d, d)}
+}
+
+func (c *fakeClock) add(d, period time.Duration) *fakeWaiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{at: c.now.Add(d), period: period, c: make(chan time.Time, 1)}
+	c.waiters = append(c.waiters, w)
+	return w
+}
+
+// waiting returns how many tickers and timers are pending.
+func (c *fakeClock) waiting() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, w := range c.waiters {
+		if !w.stop {
+			n++
+		}
+	}
+	return n
+}
+
+// advance moves the clock forward by d. Like time.Ticker, a ticker that
+// hasn't been read since it last fired drops the tick.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.stop {
+			continue
+		}
+		if !w.at.After(c.now) {
+			select {
+			case w.c <- c.now:
+			default:
+			}
+			if w.period == 0 {
+				continue
+			}
+			for !w.at.After(c.now) {
+				w.at = w.at.Add(w.period)
+			}
+		}
+		waiters = append(waiters, w)
+	}
+	c.waiters = waiters
+}
+
+type fakeTicker struct {
+	c *fakeClock
+	w *fakeWaiter
+}
+
+func (t fakeTicker) Chan() <-chan time.Time { return t.w.c }
+
+func (t fakeTicker) Stop() {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	t.w.stop = true
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// recordingNotifier hands every alert it is sent to the test.
+type recordingNotifier chan Alert
+
+func (n recordingNotifier) Name() string { return "test" }
+
+func (n recordingNotifier) Send(ctx context.Context, a Alert) error {
+	n <- a
+	return nil
+}
+
+// waitFor polls cond, in real time, until it holds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFakeClockDrivesScheduleAndAlerts(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fake := newFakeClock(start)
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	sent := make(recordingNotifier, 10)
+
+	oldClock, oldWrap, oldNotifiers, oldAlerts, oldStore := timeSource, wrapTransport, extraNotifiers, alerts, store
+	t.Cleanup(func() {
+		timeSource, wrapTransport, extraNotifiers, alerts, store = oldClock, oldWrap, oldNotifiers, oldAlerts, oldStore
+	})
+	timeSource = fake
+	wrapTransport = func(check string, rt http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			code := http.StatusOK
+			if !healthy.Load() {
+				code = http.StatusServiceUnavailable
+			}
+			return &http.Response{StatusCode: code, Body: io.NopCloser(strings.NewReader("")), Header: http.Header{}, Request: r}, nil
+		})
+	}
+	extraNotifiers = []Notifier{sent}
+	store = newMemoryStorage(newEventLog(defaultEventsSize), defaultResultsSize)
+
+	cfg := CheckConfig{Name: "fake-clock", Type: "http", URL: "http://goping.test/", Interval: time.Minute}
+	if err := applyCheckDefaults(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	list, err := newTargets(&Config{Targets: []CheckConfig{cfg}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alerts, err = newAlerter(nil, MassOutageConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deliver from goroutines the test can wait for, so none is left
+	// reading the injection points when they are put back.
+	ctx, cancel := context.WithCancel(context.Background())
+	var delivering sync.WaitGroup
+	for _, ch := range alerts.channels {
+		delivering.Add(1)
+		go func() {
+			defer delivering.Done()
+			ch.run(ctx)
+		}()
+	}
+	t.Cleanup(func() {
+		cancel()
+		delivering.Wait()
+	})
+	targets := newTargetSet(list)
+	targets.start(ctx)
+	tgt := list[0]
+
+	ranAt := func(want time.Time) func() bool {
+		return func() bool {
+			tgt.mu.Lock()
+			defer tgt.mu.Unlock()
+			return tgt.lastRun.Equal(want) && !tgt.running
+		}
+	}
+	receive := func() Alert {
+		t.Helper()
+		select {
+		case a := <-sent:
+			return a
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an alert")
+			return Alert{}
+		}
+	}
+
+	// The first run is made straight away, and the next is due a whole
+	// interval later.
+	waitFor(t, "the first run", ranAt(start))
+	waitFor(t, "the schedule's ticker", func() bool { return fake.waiting() > 0 })
+	tgt.mu.Lock()
+	next := tgt.nextRun
+	tgt.mu.Unlock()
+	if want := start.Add(time.Minute); !next.Equal(want) {
+		t.Errorf("next run = %s, want %s", next, want)
+	}
+
+	// Time standing still runs nothing.
+	time.Sleep(20 * time.Millisecond)
+	if results, _ := store.Results([]string{"fake-clock"}, time.Time{}, time.Time{}); len(results) != 1 {
+		t.Fatalf("%d results before the clock moved, want 1", len(results))
+	}
+
+	healthy.Store(false)
+	down := start.Add(time.Minute)
+	fake.advance(time.Minute)
+	waitFor(t, "the second run", ranAt(down))
+
+	a := receive()
+	if a.Status != "firing" || a.Labels["check"] != "fake-clock" || !a.StartsAt.Equal(down) {
+		t.Errorf("got %s alert for %q starting %s, want firing for %q starting %s", a.Status, a.Labels["check"], a.StartsAt, "fake-clock", down)
+	}
+
+	healthy.Store(true)
+	up := start.Add(2 * time.Minute)
+	fake.advance(time.Minute)
+	waitFor(t, "the third run", ranAt(up))
+
+	a = receive()
+	if a.Status != "resolved" || !a.EndsAt.Equal(up) {
+		t.Errorf("got %s alert ending %s, want resolved ending %s", a.Status, a.EndsAt, up)
+	}
+
+	results, err := store.Results([]string{"fake-clock"}, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var times []time.Time
+	for _, r := range results {
+		times = append(times, r.Time)
+	}
+	want := []time.Time{start, down, up}
+	if len(times) != len(want) {
+		t.Fatalf("results at %v, want %v", times, want)
+	}
+	for i := range want {
+		if !times[i].Equal(want[i]) {
+			t.Errorf("result %d at %s, want %s", i, times[i], want[i])
+		}
+	}
+}
+
+func TestInjectionThroughExportedAPI(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	oldClock := timeSource
+	t.Cleanup(func() {
+		SetClock(oldClock)
+		SetTransportWrapper(nil)
+	})
+	SetClock(newFakeClock(start))
+
+	var wrapped []string
+	SetTransportWrapper(func(check string, rt http.RoundTripper) http.RoundTripper {
+		wrapped = append(wrapped, check)
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			if r.URL.Host == "down.test" {
+				return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Header: http.Header{}, Request: r}, nil
+		})
+	})
+
+	if err := Ping(context.Background(), "http://up.test/"); err != nil {
+		t.Errorf("Ping through the wrapped transport = %v, want success", err)
+	}
+
+	r, err := Probe(context.Background(), CheckConfig{Name: "injected", Type: "http", URL: "http://down.test/"})
+	var ce *CheckError
+	if !errors.As(err, &ce) || ce.Category != categoryConnect {
+		t.Fatalf("Probe = %v, want a connect error from the wrapped transport", err)
+	}
+	if !r.Time.Equal(start) {
+		t.Errorf("result at %s, want the fake clock's %s", r.Time, start)
+	}
+	if !slices.Equal(wrapped, []string{"", "injected"}) {
+		t.Errorf("wrapped transports for %q, want goping's own and the check's", wrapped)
+	}
+}
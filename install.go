@@ -0,0 +1,207 @@
+package goping
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"text/template"
+)
+
+// The unit runs as a dynamic user with a private state directory for the
+// history and event files. CAP_NET_RAW is kept for ARP probes and binding
+// checks to an interface; everything else is locked down.
+var systemdUnit = template.Must(template.New("systemd").Parse(`[Unit]
+Description=goping uptime monitor
+Documentation=https://github.com/alexraskin/goping
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+ExecStart={{.Binary}}{{range .Args}} {{.}}{{end}}
+{{- if .EnvFile}}
+EnvironmentFile={{.EnvFile}}
+{{- end}}
+Restart=on-failure
+RestartSec=5s
+
+DynamicUser=yes
+StateDirectory=goping
+WorkingDirectory=/var/lib/goping
+AmbientCapabilities=CAP_NET_RAW
+CapabilityBoundingSet=CAP_NET_RAW
+NoNewPrivileges=yes
+ProtectSystem=strict
+ProtectHome=read-only
+PrivateTmp=yes
+PrivateDevices=yes
+ProtectClock=yes
+ProtectHostname=yes
+ProtectKernelTunables=yes
+ProtectKernelModules=yes
+ProtectKernelLogs=yes
+ProtectControlGroups=yes
+RestrictNamespaces=yes
+RestrictRealtime=yes
+RestrictSUIDSGID=yes
+LockPersonality=yes
+MemoryDenyWriteExecute=yes
+SystemCallArchitectures=native
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// launchd has no equivalent of EnvironmentFile, so the agent runs in the
+// config's directory where a .env next to it is picked up as usual.
+var launchdPlist = template.Must(template.New("launchd").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{.Label}}</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{{.Binary}}</string>
+{{- range .Args}}
+		<string>{{.}}</string>
+{{- end}}
+	</array>
+	<key>WorkingDirectory</key>
+	<string>{{.WorkDir}}</string>
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+	<key>ProcessType</key>
+	<string>Background</string>
+	<key>StandardOutPath</key>
+	<string>{{.LogFile}}</string>
+	<key>StandardErrorPath</key>
+	<string>{{.LogFile}}</string>
+</dict>
+</plist>
+`))
+
+const launchdLabel = "com.github.alexraskin.goping"
+
+type serviceUnit struct {
+	Binary  string
+	Args    []string
+	EnvFile string
+	Label   string
+	WorkDir string
+	LogFile string
+}
+
+func runInstall(args []string) int {
+	defaultFormat := "systemd"
+	if runtime.GOOS == "darwin" {
+		defaultFormat = "launchd"
+	}
+
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	format := fs.String("format", defaultFormat, "service manager to write a unit for: systemd or launchd")
+	configPath := fs.String("config", "", "path to the config file the service should use")
+	metricsPort := fs.String("metrics-port", "8080", "port the service should listen on for metrics")
+	envFile := fs.String("env-file", "", "environment file to load (systemd only)")
+	outPath := fs.String("o", "", "write the unit here instead of the default location")
+	dryRun := fs.Bool("dry-run", false, "print the unit instead of writing it")
+	fs.Parse(args)
+
+	binary, err := os.Executable()
+	if err == nil {
+		binary, err = filepath.EvalSymlinks(binary)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to locate the goping binary: %v\n", err)
+		return 1
+	}
+
+	unit := serviceUnit{
+		Binary: binary,
+		Args:   []string{"-metrics-port", *metricsPort},
+		Label:  launchdLabel,
+	}
+
+	workDir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *configPath != "" {
+		path, err := filepath.Abs(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if _, err := os.Stat(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		unit.Args = append(unit.Args, "-config", path)
+		workDir = filepath.Dir(path)
+	}
+	if *envFile != "" {
+		if unit.EnvFile, err = filepath.Abs(*envFile); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	var tmpl *template.Template
+	switch *format {
+	case "systemd":
+		tmpl = systemdUnit
+		if *outPath == "" {
+			*outPath = "/etc/systemd/system/goping.service"
+		}
+	case "launchd":
+		if *envFile != "" {
+			fmt.Fprintln(os.Stderr, "-env-file is not supported for launchd; put a .env next to the config instead")
+			return 1
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		tmpl = launchdPlist
+		unit.WorkDir = workDir
+		unit.LogFile = filepath.Join(home, "Library", "Logs", "goping.log")
+		if *outPath == "" {
+			*outPath = filepath.Join(home, "Library", "LaunchAgents", launchdLabel+".plist")
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q, expected systemd or launchd\n", *format)
+		return 1
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, unit); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *dryRun {
+		os.Stdout.Write(buf.Bytes())
+		return 0
+	}
+	if err := os.WriteFile(*outPath, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", *outPath)
+	if *format == "systemd" {
+		fmt.Fprintln(os.Stderr, "Start it with: systemctl daemon-reload && systemctl enable --now goping")
+	} else {
+		fmt.Fprintf(os.Stderr, "Start it with: launchctl load -w %s\n", *outPath)
+	}
+	return 0
+}
@@ -0,0 +1,196 @@
+package goping
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	checkValue = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goping_check_value",
+			Help: "Value extracted from the last response of a check by one of its metrics",
+		},
+		[]string{"check", "metric"},
+	)
+
+	checkValueErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_check_value_errors_total",
+			Help: "Responses a metric could not extract a numeric value from",
+		},
+		[]string{"check", "metric"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(checkValue)
+	prometheus.MustRegister(checkValueErrors)
+}
+
+// JSONMetricConfig exports the number at Path in an HTTP check's JSON
+// response as goping_check_value{metric="<Name>"}.
+type JSONMetricConfig struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+}
+
+type jsonMetric struct {
+	name string
+	raw  string
+	path []string
+}
+
+func newJSONMetrics(cfg CheckConfig) ([]jsonMetric, error) {
+	seen := make(map[string]bool, len(cfg.Metrics))
+	out := make([]jsonMetric, 0, len(cfg.Metrics))
+	for i, m := range cfg.Metrics {
+		if m.Name == "" {
+			return nil, fmt.Errorf("check %q: metric %d: name is required", cfg.Name, i)
+		}
+		if seen[m.Name] {
+			return nil, fmt.Errorf("check %q: duplicate metric %q", cfg.Name, m.Name)
+		}
+		seen[m.Name] = true
+
+		path, err := parseJSONPath(m.Path)
+		if err != nil {
+			return nil, fmt.Errorf("check %q: metric %q: %w", cfg.Name, m.Name, err)
+		}
+		out = append(out, jsonMetric{name: m.Name, raw: m.Path, path: path})
+		checkValueErrors.WithLabelValues(cfg.Name, m.Name)
+	}
+	return out, nil
+}
+
+// parseJSONPath accepts the subset of JSONPath that selects a single value:
+// $.a.b, $.items[0], $.items[-1] and $['key.with.dots']. The leading $ is
+// optional, so the dotted paths of ignore_paths work too.
+func parseJSONPath(s string) ([]string, error) {
+	if s == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	rest := strings.TrimPrefix(s, "$")
+	var path []string
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated ['", s)
+			}
+			path = append(path, rest[2:end])
+			rest = rest[end+2:]
+			continue
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: unterminated [", s)
+			}
+			if _, err := strconv.Atoi(rest[1:end]); err != nil {
+				return nil, fmt.Errorf("invalid path %q: %q is not an array index", s, rest[1:end])
+			}
+			path = append(path, rest[1:end])
+			rest = rest[end+1:]
+			continue
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return nil, fmt.Errorf("invalid path %q: empty key", s)
+		}
+		path = append(path, rest[:end])
+		rest = rest[end:]
+	}
+	return path, nil
+}
+
+// lookupPath returns the value at path in v. Negative array indexes count
+// from the end.
+func lookupPath(v any, path []string) (any, bool) {
+	for _, key := range path {
+		switch node := v.(type) {
+		case map[string]any:
+			child, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil {
+				return nil, false
+			}
+			if i < 0 {
+				i += len(node)
+			}
+			if i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// numericValue converts a JSON value to a gauge value. Booleans become 0 or 1
+// and numeric strings are parsed, since many APIs quote large numbers.
+func numericValue(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// exportJSONMetrics sets the check's value gauges from a response body. A
+// metric that can't be extracted is dropped rather than left at a stale
+// value; it doesn't fail the check.
+func exportJSONMetrics(check string, metrics []jsonMetric, body []byte) {
+	drop := func(m jsonMetric, err error) {
+		checkValue.DeleteLabelValues(check, m.name)
+		checkValueErrors.WithLabelValues(check, m.name).Inc()
+		logger.Warn("Failed to extract metric from response", "check", check, "metric", m.name, "error", err)
+	}
+
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		for _, m := range metrics {
+			drop(m, fmt.Errorf("invalid JSON: %w", err))
+		}
+		return
+	}
+
+	for _, m := range metrics {
+		v, ok := lookupPath(doc, m.path)
+		if !ok {
+			drop(m, fmt.Errorf("no value at %s", m.raw))
+			continue
+		}
+		value, ok := numericValue(v)
+		if !ok {
+			drop(m, fmt.Errorf("value at %s is not a number", m.raw))
+			continue
+		}
+		checkValue.WithLabelValues(check, m.name).Set(value)
+	}
+}
@@ -0,0 +1,149 @@
+package goping
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "$.a.b", want: []string{"a", "b"}},
+		{in: "a.b", want: []string{"a", "b"}},
+		{in: "$.items[0].name", want: []string{"items", "0", "name"}},
+		{in: "$.items[-1]", want: []string{"items", "-1"}},
+		{in: "$['key.with.dots'].x", want: []string{"key.with.dots", "x"}},
+		{in: "$[2][3]", want: []string{"2", "3"}},
+		{in: "$", want: nil},
+		{in: "", wantErr: true},
+		{in: "$.items[x]", wantErr: true},
+		{in: "$.items[0", wantErr: true},
+		{in: "$['a", wantErr: true},
+		{in: "$.a..b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseJSONPath(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseJSONPath(%q) = %q, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q): %v", tt.in, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseJSONPath(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathValue(t *testing.T) {
+	const body = `{
+		"status": {"healthy": true, "degraded": false},
+		"queue": {"depth": 42, "bytes": "1234567890123"},
+		"workers": [{"busy": 3}, {"busy": 5}],
+		"a.b": {"c": 1.5},
+		"name": "api"
+	}`
+	var doc any
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want float64
+		ok   bool
+	}{
+		{"$.queue.depth", 42, true},
+		{"$.queue.bytes", 1234567890123, true},
+		{"$.status.healthy", 1, true},
+		{"$.status.degraded", 0, true},
+		{"$.workers[1].busy", 5, true},
+		{"$.workers[-2].busy", 3, true},
+		{"$['a.b'].c", 1.5, true},
+		{"$.workers[2].busy", 0, false},
+		{"$.workers[-3].busy", 0, false},
+		{"$.queue.missing", 0, false},
+		{"$.queue.depth.more", 0, false},
+		{"$.workers.busy", 0, false},
+		{"$.name", 0, false},
+		{"$.status", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := parseJSONPath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, found := lookupPath(doc, path)
+			got, ok := 0.0, false
+			if found {
+				got, ok = numericValue(v)
+			}
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("value at %s = %v, %t, want %v, %t", tt.path, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestExportJSONMetrics(t *testing.T) {
+	const check = "jsonmetrics-test"
+	t.Cleanup(func() {
+		checkValue.DeletePartialMatch(map[string]string{"check": check})
+		checkValueErrors.DeletePartialMatch(map[string]string{"check": check})
+	})
+
+	metrics, err := newJSONMetrics(CheckConfig{Name: check, Metrics: []JSONMetricConfig{
+		{Name: "depth", Path: "$.queue.depth"},
+		{Name: "busy", Path: "$.workers[0].busy"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exportJSONMetrics(check, metrics, []byte(`{"queue": {"depth": 7}, "workers": [{"busy": 2}]}`))
+	if got := testutil.ToFloat64(checkValue.WithLabelValues(check, "depth")); got != 7 {
+		t.Errorf("depth = %v, want 7", got)
+	}
+
+	// A value that disappears is dropped, not left stale.
+	exportJSONMetrics(check, metrics, []byte(`{"queue": {"depth": 9}, "workers": []}`))
+	if got := testutil.ToFloat64(checkValue.WithLabelValues(check, "depth")); got != 9 {
+		t.Errorf("depth = %v, want 9", got)
+	}
+	if n := testutil.CollectAndCount(checkValue, "goping_check_value"); n != 1 {
+		t.Errorf("%d value series after busy went missing, want 1", n)
+	}
+	if got := testutil.ToFloat64(checkValueErrors.WithLabelValues(check, "busy")); got != 1 {
+		t.Errorf("busy errors = %v, want 1", got)
+	}
+}
+
+func TestNewJSONMetricsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		metrics []JSONMetricConfig
+	}{
+		{"no name", []JSONMetricConfig{{Path: "$.a"}}},
+		{"duplicate", []JSONMetricConfig{{Name: "a", Path: "$.a"}, {Name: "a", Path: "$.b"}}},
+		{"bad path", []JSONMetricConfig{{Name: "a", Path: "$.a[b]"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newJSONMetrics(CheckConfig{Name: "x", Metrics: tt.metrics}); err == nil {
+				t.Error("newJSONMetrics succeeded, want an error")
+			}
+		})
+	}
+}
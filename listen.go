@@ -0,0 +1,110 @@
+package goping
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Socket states from include/net/tcp_states.h as shown in /proc/net/{tcp,udp}.
+const (
+	procStateListen = "0A"
+	procStateClose  = "07"
+)
+
+type listenChecker struct {
+	port     int
+	protocol string
+}
+
+func newListenChecker(cfg CheckConfig) (*listenChecker, error) {
+	if cfg.Port <= 0 || cfg.Port > 65535 {
+		return nil, fmt.Errorf("check %q: port must be between 1 and 65535", cfg.Name)
+	}
+
+	protocol := cfg.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	if protocol != "tcp" && protocol != "udp" {
+		return nil, fmt.Errorf("check %q: protocol must be tcp or udp", cfg.Name)
+	}
+
+	return &listenChecker{port: cfg.Port, protocol: protocol}, nil
+}
+
+// check looks for a bound socket in /proc/net. Where /proc is unavailable,
+// TCP ports fall back to dialing localhost.
+func (c *listenChecker) check(ctx context.Context) error {
+	bound, err := c.procBound()
+	if err == nil {
+		if !bound {
+			return fmt.Errorf("no %s socket bound to port %d", c.protocol, c.port)
+		}
+		return nil
+	}
+
+	if c.protocol != "tcp" {
+		return fmt.Errorf("cannot inspect udp sockets: %w", err)
+	}
+
+	logger.Debug("Falling back to dialing localhost", "port", c.port, "error", err)
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort("localhost", strconv.Itoa(c.port)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (c *listenChecker) procBound() (bool, error) {
+	state := procStateListen
+	if c.protocol == "udp" {
+		state = procStateClose
+	}
+
+	found := false
+	for _, suffix := range []string{"", "6"} {
+		ok, err := procHasSocket("/proc/net/"+c.protocol+suffix, c.port, state)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && suffix == "6" {
+				// IPv6 disabled
+				continue
+			}
+			return false, err
+		}
+		found = found || ok
+	}
+
+	return found, nil
+}
+
+func procHasSocket(path string, port int, state string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	want := fmt.Sprintf(":%04X", port)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		if strings.HasSuffix(fields[1], want) && fields[3] == state {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
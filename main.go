@@ -1,12 +1,19 @@
-package main
+// Package goping is an uptime monitor: it runs checks against HTTP, TCP, DNS
+// and other targets, exports the results as Prometheus metrics and sends
+// alerts when targets go down. The goping command in cmd/goping runs it;
+// programs that embed it can add storage drivers and notifiers of their own.
+package goping
 
 import (
 	"context"
 	"flag"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -19,7 +26,7 @@ import (
 )
 
 var (
-	logger *slog.Logger
+	logger = slog.Default()
 
 	retryClient = retryablehttp.NewClient()
 
@@ -86,55 +93,83 @@ func getEnv(key string) string {
 	return strings.TrimSpace(value)
 }
 
-func ping(url string) {
+// Ping requests url the way goping sends its keep-alive to the webhook,
+// retrying server errors. A failure is returned classified like a check's,
+// with nil meaning success.
+func Ping(ctx context.Context, url string) *CheckError {
 	start := time.Now()
 
-	r, err := retryablehttp.NewRequest("GET", url, nil)
+	r, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		logger.Error("Failed to create request", "error", err)
 		pingErrors.WithLabelValues("request_creation").Inc()
-		return
+		return newCheckError(categoryOther, "request", false, fmt.Errorf("failed to create request: %w", err))
 	}
 
 	resp, err := retryClient.Do(r)
 	duration := time.Since(start).Seconds()
 
 	if err != nil {
-		logger.Error("Failed to send request", "error", err)
 		pingRequestsTotal.WithLabelValues("error").Inc()
 		pingDuration.WithLabelValues("error").Observe(duration)
 		pingErrors.WithLabelValues("request_failed").Inc()
-		return
+		return classifyCheckError(err)
 	}
 
 	defer resp.Body.Close()
 
 	status := "success"
+	var cerr *CheckError
 	if resp.StatusCode >= 400 {
 		status = "client_error"
 		if resp.StatusCode >= 500 {
 			status = "server_error"
 		}
-		logger.Warn("Request returned non-success status", "status_code", resp.StatusCode, "url", url)
+		cerr = statusError(resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode))
 	} else {
 		logger.Info("Ping successful", "status_code", resp.StatusCode, "duration", duration)
 	}
 
 	pingRequestsTotal.WithLabelValues(status).Inc()
 	pingDuration.WithLabelValues(status).Observe(duration)
+	return cerr
 }
 
-func startMetricsServer(port string) *http.Server {
+// pingWebhook pings url and logs the outcome.
+func pingWebhook(ctx context.Context, url string) {
+	if err := Ping(ctx, url); err != nil {
+		logger.Error("Failed to ping webhook", "url", url, "error", err, "category", err.Category, "phase", err.Phase)
+	}
+}
+
+func startMetricsServer(port string, cfg *Config, targets *targetSet, access *accessList, gatherer prometheus.Gatherer) *http.Server {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	registerAPI(mux, cfg.API, targets)
+	registerDashboard(mux, cfg.API, targets)
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}),
+	))
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !targets.ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	var handler http.Handler = mux
+	if cfg.API.ReadOnly {
+		handler = readOnly(handler)
+	}
+	handler = access.middleware(handler)
 
 	server := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: handler,
 	}
 
 	go func() {
@@ -147,13 +182,50 @@ func startMetricsServer(port string) *http.Server {
 	return server
 }
 
-func main() {
+// Main runs the goping command with the arguments in os.Args. Storage
+// drivers and notifiers registered before it is called are available to the
+// config.
+func Main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate-config":
+			setupLogger(false, os.Stdout)
+			os.Exit(runMigrateConfig(os.Args[2:]))
+		case "snapshot":
+			setupLogger(false, os.Stdout)
+			os.Exit(runSnapshot(os.Args[2:]))
+		case "install":
+			setupLogger(false, os.Stdout)
+			os.Exit(runInstall(os.Args[2:]))
+		case "healthcheck":
+			setupLogger(false, os.Stdout)
+			os.Exit(runHealthcheck(os.Args[2:]))
+		case "notify-test":
+			setupLogger(false, os.Stderr)
+			os.Exit(runNotifyTest(os.Args[2:]))
+		case "targets":
+			setupLogger(false, os.Stderr)
+			os.Exit(runTargets(os.Args[2:]))
+		}
+	}
+
 	debug := flag.Bool("debug", false, "enable debug logging")
 	metricsPort := flag.String("metrics-port", "8080", "port to listen on for metrics")
+	configPath := flag.String("config", "", "path to a YAML file defining additional checks")
+	restorePath := flag.String("restore", "", "path to a snapshot of runtime state to restore at startup")
+	readOnlyMode := flag.Bool("read-only", false, "reject all API and dashboard requests that change state")
+	notifyTest := flag.Bool("notify-test", false, "send a test notification through every notifier at startup")
+	reportPath := flag.String("report", "", "write a JSON summary of the run to this file on shutdown")
+	resultsStdout := flag.Bool("results-stdout", false, "write every check result to stdout as a line of JSON, moving logs to stderr")
 	flag.Parse()
 
 	// Initialize logger once
-	logger = setupLogger(*debug)
+	var logOutput io.Writer = os.Stdout
+	if *resultsStdout {
+		logOutput = os.Stderr
+	}
+	logger = setupLogger(*debug, logOutput)
+	started := timeSource.Now()
 
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -166,12 +238,121 @@ func main() {
 		os.Exit(1)
 	}
 
-	metricsServer := startMetricsServer(*metricsPort)
+	wrapRetryClient()
+
+	cfg := &Config{}
+	if *configPath != "" {
+		var err error
+		cfg, err = loadConfig(*configPath)
+		if err != nil {
+			logger.Error("Failed to load config", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.Heartbeat.URL == "" {
+		cfg.Heartbeat.URL = getEnv("HEARTBEAT_URL")
+	}
+	if cfg.API.Token == "" {
+		cfg.API.Token = getEnv("API_TOKEN")
+	}
+	if *readOnlyMode {
+		cfg.API.ReadOnly = true
+	}
+
+	list, err := newTargets(cfg)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+
+	hostLimits, err = newHostLimiter(cfg.RateLimit)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+
+	access, err := newAccessList(cfg.API.Allow)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+
+	gatherer, err := newMetricsGatherer(cfg.Metrics, prometheus.DefaultGatherer)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+	targets := newTargetSet(list)
+
+	// Registered first so it runs last, after events, history and sinks
+	// have been flushed.
+	defer func() {
+		report := newShutdownReport(started, targets)
+		report.log()
+		if *reportPath != "" {
+			if err := report.write(*reportPath); err != nil {
+				logger.Error("Failed to write shutdown report", "error", err)
+			}
+		}
+	}()
+
+	if *restorePath != "" {
+		if err := restoreSnapshot(*restorePath, targets); err != nil {
+			logger.Error("Failed to restore snapshot", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	alerts, err = newAlerter(cfg.Notifiers, cfg.MassOutage)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+
+	maintenance, err := newMaintenanceSources(cfg.Maintenance)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+
+	sinks, err = newSinks(cfg.Sinks)
+	if err != nil {
+		logger.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+	if *resultsStdout {
+		sinks = append(sinks, newStdoutSink(os.Stdout))
+	}
+
+	store, err = openStorage(cfg.Storage, cfg.Events)
+	if err != nil {
+		logger.Error("Failed to open storage", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := store.Close(); err != nil {
+			logger.Error("Failed to close storage", "error", err)
+		}
+	}()
+
+	history, err = openHistory(cfg.History)
+	if err != nil {
+		logger.Error("Failed to open history", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := history.close(); err != nil {
+			logger.Error("Failed to save history", "error", err)
+		}
+	}()
+
+	metricsServer := startMetricsServer(*metricsPort, cfg, targets, access, gatherer)
 
 	go func() {
-		uptimeTicker := time.NewTicker(1 * time.Second)
+		uptimeTicker := timeSource.NewTicker(1 * time.Second)
 		defer uptimeTicker.Stop()
-		for range uptimeTicker.C {
+		for range uptimeTicker.Chan() {
 			uptime.WithLabelValues().Inc()
 		}
 	}()
@@ -195,24 +376,46 @@ func main() {
 		}
 	}()
 
-	ticker := time.NewTicker(15 * time.Minute)
+	go alerts.run(ctx)
+	if *notifyTest {
+		go testNotifiers(ctx, alerts)
+	}
+	go clock.run(ctx)
+	go history.run(ctx)
+	go startHeartbeat(ctx, cfg.Heartbeat, targets)
+	for _, m := range maintenance {
+		go m.run(ctx)
+	}
+
+	var sinksDone sync.WaitGroup
+	for _, s := range sinks {
+		sinksDone.Add(1)
+		go func() {
+			defer sinksDone.Done()
+			s.run(ctx)
+		}()
+	}
+	defer sinksDone.Wait()
+	targets.start(ctx)
+
+	ticker := timeSource.NewTicker(15 * time.Minute)
 	defer ticker.Stop()
 
-	ping(webhookURL)
+	pingWebhook(ctx, webhookURL)
 
 	for {
 		select {
 		case <-ctx.Done():
 			logger.Info("goping stopped")
 			return
-		case <-ticker.C:
-			ping(webhookURL)
+		case <-ticker.Chan():
+			pingWebhook(ctx, webhookURL)
 		}
 	}
 }
 
-func setupLogger(debug bool) *slog.Logger {
-	logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+func setupLogger(debug bool, w io.Writer) *slog.Logger {
+	logger = slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
 		Level: func() slog.Level {
 			if debug {
 				return slog.LevelDebug
@@ -0,0 +1,12 @@
+package goping
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	logger = slog.New(slog.DiscardHandler)
+	os.Exit(m.Run())
+}
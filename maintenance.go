@@ -0,0 +1,296 @@
+package goping
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultMaintenanceInterval = 5 * time.Minute
+
+var maintenanceSyncs = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goping_maintenance_syncs_total",
+		Help: "Total number of maintenance window syncs",
+	},
+	[]string{"source", "status"},
+)
+
+func init() {
+	prometheus.MustRegister(maintenanceSyncs)
+}
+
+// MaintenanceConfig describes an external source of maintenance windows.
+// Windows become silences owned by the source and are replaced on every sync.
+type MaintenanceConfig struct {
+	Name     string        `yaml:"name"`
+	URL      string        `yaml:"url"`
+	Format   string        `yaml:"format"`
+	Interval time.Duration `yaml:"interval"`
+	Matchers string        `yaml:"matchers"`
+}
+
+// maintenanceWindow is the JSON format, and what iCal events are parsed into.
+type maintenanceWindow struct {
+	ID       string    `json:"id"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Matchers string    `json:"matchers"`
+	Comment  string    `json:"comment"`
+}
+
+type maintenanceSource struct {
+	cfg      MaintenanceConfig
+	matchers []matcher
+}
+
+func newMaintenanceSources(cfgs []MaintenanceConfig) ([]*maintenanceSource, error) {
+	var sources []*maintenanceSource
+	for i, c := range cfgs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("maintenance source %d: name is required", i)
+		}
+		if c.URL == "" {
+			return nil, fmt.Errorf("maintenance source %q: url is required", c.Name)
+		}
+		switch c.Format {
+		case "":
+			c.Format = "ical"
+		case "ical", "json":
+		default:
+			return nil, fmt.Errorf("maintenance source %q: format must be ical or json", c.Name)
+		}
+		if c.Interval <= 0 {
+			c.Interval = defaultMaintenanceInterval
+		}
+
+		matchers, err := parseMatchers(c.Matchers)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance source %q: %w", c.Name, err)
+		}
+
+		sources = append(sources, &maintenanceSource{cfg: c, matchers: matchers})
+	}
+	return sources, nil
+}
+
+func (s *maintenanceSource) owner() string {
+	return "maintenance:" + s.cfg.Name
+}
+
+func (s *maintenanceSource) run(ctx context.Context) {
+	ticker := timeSource.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sync(ctx); err != nil {
+			// Keep the windows from the last successful sync.
+			logger.Error("Failed to sync maintenance windows", "source", s.cfg.Name, "error", err)
+			maintenanceSyncs.WithLabelValues(s.cfg.Name, "error").Inc()
+		} else {
+			maintenanceSyncs.WithLabelValues(s.cfg.Name, "success").Inc()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+		}
+	}
+}
+
+func (s *maintenanceSource) sync(ctx context.Context) error {
+	r, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, s.cfg.URL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := retryClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var windows []maintenanceWindow
+	if s.cfg.Format == "json" {
+		err = json.NewDecoder(resp.Body).Decode(&windows)
+	} else {
+		windows, err = parseICal(resp.Body)
+	}
+	if err != nil {
+		return err
+	}
+
+	now := timeSource.Now()
+	var out []silence
+	for _, w := range windows {
+		sil := silence{
+			StartsAt: w.Start,
+			EndsAt:   w.End,
+			Comment:  w.Comment,
+			Matchers: s.matchers,
+		}
+		if w.Matchers != "" {
+			if sil.Matchers, err = parseMatchers(w.Matchers); err != nil {
+				logger.Warn("Skipping maintenance window", "source", s.cfg.Name, "id", w.ID, "error", err)
+				continue
+			}
+		}
+		if sil.EndsAt.Before(now) {
+			continue
+		}
+		if err := sil.validate(now); err != nil {
+			logger.Warn("Skipping maintenance window", "source", s.cfg.Name, "id", w.ID, "error", err)
+			continue
+		}
+		sil.ID = s.silenceID(w, sil.Matchers)
+		out = append(out, sil)
+	}
+
+	silences.replace(s.owner(), out)
+	logger.Debug("Synced maintenance windows", "source", s.cfg.Name, "windows", len(out))
+	return nil
+}
+
+// silenceID derives a stable ID so a window keeps its silence across syncs,
+// and changes to it replace the silence.
+func (s *maintenanceSource) silenceID(w maintenanceWindow, matchers []matcher) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%d\x00%d", s.cfg.Name, w.ID, w.Start.Unix(), w.End.Unix())
+	for _, m := range matchers {
+		fmt.Fprintf(h, "\x00%s", m)
+	}
+	b := h.Sum(nil)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// parseICal extracts VEVENTs from an iCalendar feed. Recurrence rules are not
+// expanded; each event is a single window. Events may override the source's
+// matchers with an X-GOPING-MATCHERS property.
+func parseICal(r io.Reader) ([]maintenanceWindow, error) {
+	lines, err := unfoldICal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		windows []maintenanceWindow
+		w       *maintenanceWindow
+		allDay  bool
+	)
+	for _, line := range lines {
+		name, params, value, ok := parseICalLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			w = &maintenanceWindow{}
+			allDay = false
+		case name == "END" && value == "VEVENT":
+			if w == nil {
+				continue
+			}
+			if w.End.IsZero() && allDay {
+				w.End = w.Start.AddDate(0, 0, 1)
+			}
+			if w.Start.IsZero() || w.End.IsZero() {
+				logger.Warn("Skipping calendar event without start and end", "uid", w.ID)
+			} else {
+				windows = append(windows, *w)
+			}
+			w = nil
+		case w == nil:
+			continue
+		case name == "UID":
+			w.ID = value
+		case name == "SUMMARY":
+			w.Comment = unescapeICal(value)
+		case name == "X-GOPING-MATCHERS":
+			w.Matchers = unescapeICal(value)
+		case name == "DTSTART" || name == "DTEND":
+			t, date, err := parseICalTime(params, value)
+			if err != nil {
+				return nil, fmt.Errorf("event %s: %w", w.ID, err)
+			}
+			if name == "DTSTART" {
+				w.Start = t
+				allDay = date
+			} else {
+				w.End = t
+			}
+		}
+	}
+
+	return windows, nil
+}
+
+func unfoldICal(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+func parseICalLine(line string) (name string, params map[string]string, value string, ok bool) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:i], ";")
+	params = make(map[string]string)
+	for _, p := range parts[1:] {
+		if k, v, found := strings.Cut(p, "="); found {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[i+1:], true
+}
+
+// parseICalTime handles UTC, TZID-qualified and floating date-times, and
+// all-day dates. Floating times are taken as local time.
+func parseICalTime(params map[string]string, value string) (t time.Time, date bool, err error) {
+	loc := time.Local
+	if tzid := params["TZID"]; tzid != "" {
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+
+	switch {
+	case params["VALUE"] == "DATE" || len(value) == 8:
+		t, err = time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	}
+	return t, false, err
+}
+
+func unescapeICal(s string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
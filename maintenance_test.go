@@ -0,0 +1,126 @@
+package goping
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICal(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	event := func(lines ...string) string {
+		return "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	}
+	tests := []struct {
+		name string
+		ics  string
+		want []maintenanceWindow
+	}{
+		{
+			name: "utc",
+			ics:  event("UID:1", "SUMMARY:DB upgrade", "DTSTART:20240501T020000Z", "DTEND:20240501T040000Z"),
+			want: []maintenanceWindow{{
+				ID:      "1",
+				Start:   time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC),
+				End:     time.Date(2024, 5, 1, 4, 0, 0, 0, time.UTC),
+				Comment: "DB upgrade",
+			}},
+		},
+		{
+			name: "tzid",
+			ics:  event("UID:2", `DTSTART;TZID="Europe/Berlin":20240501T020000`, "DTEND;TZID=Europe/Berlin:20240501T040000"),
+			want: []maintenanceWindow{{
+				ID:    "2",
+				Start: time.Date(2024, 5, 1, 2, 0, 0, 0, berlin),
+				End:   time.Date(2024, 5, 1, 4, 0, 0, 0, berlin),
+			}},
+		},
+		{
+			name: "floating",
+			ics:  event("UID:3", "DTSTART:20240501T020000", "DTEND:20240501T040000"),
+			want: []maintenanceWindow{{
+				ID:    "3",
+				Start: time.Date(2024, 5, 1, 2, 0, 0, 0, time.Local),
+				End:   time.Date(2024, 5, 1, 4, 0, 0, 0, time.Local),
+			}},
+		},
+		{
+			name: "all day without end",
+			ics:  event("UID:4", "DTSTART;VALUE=DATE:20240501"),
+			want: []maintenanceWindow{{
+				ID:    "4",
+				Start: time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local),
+				End:   time.Date(2024, 5, 2, 0, 0, 0, 0, time.Local),
+			}},
+		},
+		{
+			name: "folded and escaped",
+			ics: event("UID:5", "SUMMARY:Network\\, DNS\\; and", "  more", "X-GOPING-MATCHERS:check=~api-.*\\,env=prod",
+				"DTSTART:20240501T020000Z", "DTEND:20240501T030000Z"),
+			want: []maintenanceWindow{{
+				ID:       "5",
+				Start:    time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC),
+				End:      time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC),
+				Matchers: "check=~api-.*,env=prod",
+				Comment:  "Network, DNS; and more",
+			}},
+		},
+		{
+			name: "without end",
+			ics:  event("UID:6", "DTSTART:20240501T020000Z"),
+			want: nil,
+		},
+		{
+			name: "properties outside events",
+			ics:  "BEGIN:VCALENDAR\r\nDTSTART:20240501T020000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: nil,
+		},
+		{
+			name: "two events",
+			ics: "BEGIN:VCALENDAR\n" +
+				"BEGIN:VEVENT\nUID:a\nDTSTART:20240501T020000Z\nDTEND:20240501T030000Z\nEND:VEVENT\n" +
+				"BEGIN:VEVENT\nUID:b\nDTSTART:20240502T020000Z\nDTEND:20240502T030000Z\nEND:VEVENT\n" +
+				"END:VCALENDAR\n",
+			want: []maintenanceWindow{
+				{ID: "a", Start: time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC), End: time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)},
+				{ID: "b", Start: time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC), End: time.Date(2024, 5, 2, 3, 0, 0, 0, time.UTC)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseICal(strings.NewReader(tt.ics))
+			if err != nil {
+				t.Fatalf("parseICal: %v", err)
+			}
+			equal := func(a, b maintenanceWindow) bool {
+				return a.ID == b.ID && a.Start.Equal(b.Start) && a.End.Equal(b.End) && a.Matchers == b.Matchers && a.Comment == b.Comment
+			}
+			if !slices.EqualFunc(got, tt.want, equal) {
+				t.Errorf("parseICal = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		ics  string
+	}{
+		{"bad time", "BEGIN:VEVENT\nUID:1\nDTSTART:2024-05-01\nEND:VEVENT\n"},
+		{"unknown zone", "BEGIN:VEVENT\nUID:1\nDTSTART;TZID=Nowhere/Special:20240501T020000\nEND:VEVENT\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := parseICal(strings.NewReader(tt.ics)); err == nil {
+				t.Errorf("parseICal = %+v, want an error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package goping
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const defaultMassOutageWindow = time.Minute
+
+var (
+	alertsCollapsed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_alerts_collapsed_total",
+			Help: "Alerts held back because they were part of a mass outage",
+		},
+		[]string{"check"},
+	)
+
+	massOutageActive = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "goping_mass_outage",
+			Help: "1 while a mass outage is in progress and individual alerts are collapsed",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(alertsCollapsed)
+	prometheus.MustRegister(massOutageActive)
+}
+
+// MassOutageConfig collapses alerts when many checks go down at once, which
+// usually means goping's own network is at fault rather than every target.
+type MassOutageConfig struct {
+	// Threshold is how many checks must go down within Window; 0 disables.
+	Threshold int           `yaml:"threshold"`
+	Window    time.Duration `yaml:"window"`
+}
+
+// massOutage tracks which checks are down and decides which alerts to
+// deliver. Once Threshold checks have gone down within Window, a single
+// MassOutage alert is sent and further alerts are held back. The outage is
+// over when fewer than Threshold checks are down; checks that are still down
+// a Window later then alert individually, so a staggered recovery doesn't
+// page for every check that is merely slower to come back.
+type massOutage struct {
+	threshold int
+	window    time.Duration
+
+	// release delivers held-back alerts once they are due.
+	release func(Alert)
+
+	mu        sync.Mutex
+	recent    []time.Time
+	down      map[string]Alert
+	collapsed map[string]bool
+	active    bool
+	since     time.Time
+}
+
+func newMassOutage(cfg MassOutageConfig) (*massOutage, error) {
+	if cfg.Threshold < 0 {
+		return nil, fmt.Errorf("mass_outage: threshold must not be negative")
+	}
+	if cfg.Threshold == 0 {
+		return nil, nil
+	}
+	if cfg.Threshold < 2 {
+		return nil, fmt.Errorf("mass_outage: threshold must be at least 2")
+	}
+	window := cfg.Window
+	if window <= 0 {
+		window = defaultMassOutageWindow
+	}
+	return &massOutage{
+		threshold: cfg.Threshold,
+		window:    window,
+		down:      make(map[string]Alert),
+		collapsed: make(map[string]bool),
+	}, nil
+}
+
+// filter returns the alerts to deliver in place of al. A nil massOutage
+// passes every alert through.
+func (m *massOutage) filter(al Alert, now time.Time) []Alert {
+	if m == nil {
+		return []Alert{al}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	check := al.Labels["check"]
+	if al.Status == "firing" {
+		m.down[check] = al
+
+		m.recent = append(m.recent, now)
+		i := 0
+		for i < len(m.recent) && now.Sub(m.recent[i]) > m.window {
+			i++
+		}
+		m.recent = m.recent[i:]
+
+		if m.active {
+			m.collapse(check)
+			return nil
+		}
+		if len(m.recent) < m.threshold {
+			return []Alert{al}
+		}
+
+		m.active = true
+		m.since = now
+		massOutageActive.Set(1)
+		m.collapse(check)
+		logger.Warn("Mass outage detected, collapsing alerts", "checks", len(m.recent), "window", m.window)
+		return []Alert{m.alert(now)}
+	}
+
+	delete(m.down, check)
+	var out []Alert
+	if m.collapsed[check] {
+		delete(m.collapsed, check)
+		alertsCollapsed.WithLabelValues(check).Inc()
+	} else {
+		out = append(out, al)
+	}
+
+	if m.active && len(m.down) < m.threshold {
+		m.active = false
+		m.recent = nil
+		massOutageActive.Set(0)
+		out = append(out, m.alert(now))
+		logger.Info("Mass outage over", "down", len(m.down))
+		if len(m.collapsed) > 0 {
+			due := timeSource.After(m.window)
+			go func() {
+				<-due
+				m.flush()
+			}()
+		}
+	}
+	return out
+}
+
+// flush releases the held-back alerts of checks that are still down after an
+// outage, unless another one has started since.
+func (m *massOutage) flush() {
+	m.mu.Lock()
+	if m.active {
+		m.mu.Unlock()
+		return
+	}
+	var out []Alert
+	for _, name := range slices.Sorted(maps.Keys(m.collapsed)) {
+		out = append(out, m.down[name])
+	}
+	clear(m.collapsed)
+	m.mu.Unlock()
+
+	if len(out) > 0 {
+		logger.Info("Alerting checks still down after mass outage", "checks", len(out))
+	}
+	for _, al := range out {
+		m.release(al)
+	}
+}
+
+func (m *massOutage) collapse(check string) {
+	m.collapsed[check] = true
+	alertsCollapsed.WithLabelValues(check).Inc()
+}
+
+// alert describes the outage: firing while active, resolved once it is over.
+func (m *massOutage) alert(now time.Time) Alert {
+	a := Alert{
+		Labels:   map[string]string{"alertname": "MassOutage"},
+		StartsAt: m.since,
+	}
+	if !m.active {
+		a.Status = "resolved"
+		a.EndsAt = now
+		a.Summary = "Mass outage is over"
+		return a
+	}
+
+	names := slices.Sorted(maps.Keys(m.down))
+	a.Status = "firing"
+	a.Summary = fmt.Sprintf("%d checks are down, alerts are collapsed until fewer than %d are: %s",
+		len(names), m.threshold, strings.Join(names, ", "))
+	return a
+}
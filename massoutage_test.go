@@ -0,0 +1,129 @@
+package goping
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMassOutageFilter(t *testing.T) {
+	type step struct {
+		at     time.Duration
+		check  string
+		status string
+		want   []string // status and check or alertname of each delivered alert
+	}
+	tests := []struct {
+		name      string
+		threshold int
+		window    time.Duration
+		steps     []step
+	}{
+		{
+			name:      "spread out",
+			threshold: 2,
+			window:    time.Minute,
+			steps: []step{
+				{0, "a", "firing", []string{"firing a"}},
+				{2 * time.Minute, "b", "firing", []string{"firing b"}},
+				{3 * time.Minute, "a", "resolved", []string{"resolved a"}},
+			},
+		},
+		{
+			name:      "collapsed",
+			threshold: 3,
+			window:    time.Minute,
+			steps: []step{
+				{0, "a", "firing", []string{"firing a"}},
+				{10 * time.Second, "b", "firing", []string{"firing b"}},
+				{20 * time.Second, "c", "firing", []string{"firing MassOutage"}},
+				{30 * time.Second, "d", "firing", nil},
+				// Recoveries of collapsed checks stay quiet, others don't.
+				{time.Minute, "d", "resolved", nil},
+				{time.Minute, "a", "resolved", []string{"resolved a", "resolved MassOutage"}},
+				{time.Minute, "b", "resolved", []string{"resolved b"}},
+			},
+		},
+		{
+			name:      "window slides",
+			threshold: 2,
+			window:    time.Minute,
+			steps: []step{
+				{0, "a", "firing", []string{"firing a"}},
+				{61 * time.Second, "b", "firing", []string{"firing b"}},
+				{2 * time.Minute, "c", "firing", []string{"firing MassOutage"}},
+			},
+		},
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newMassOutage(MassOutageConfig{Threshold: tt.threshold, Window: tt.window})
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.release = func(Alert) {}
+			for _, s := range tt.steps {
+				al := Alert{Labels: map[string]string{"check": s.check}, Status: s.status}
+				if got := describeAlerts(m.filter(al, start.Add(s.at))); !slices.Equal(got, s.want) {
+					t.Errorf("%s %s at %s delivered %q, want %q", s.check, s.status, s.at, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMassOutageReleasesChecksStillDown(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	fake := newFakeClock(start)
+	old := timeSource
+	t.Cleanup(func() { timeSource = old })
+	timeSource = fake
+
+	m, err := newMassOutage(MassOutageConfig{Threshold: 2, Window: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	released := make(chan Alert, 10)
+	m.release = func(al Alert) { released <- al }
+
+	for i, check := range []string{"a", "b", "c", "d"} {
+		m.filter(Alert{Labels: map[string]string{"check": check}, Status: "firing"}, start.Add(time.Duration(i)*time.Second))
+	}
+	// b, c and d were collapsed. The outage ends when c recovers; d is
+	// still down a window later and alerts on its own.
+	m.filter(Alert{Labels: map[string]string{"check": "a"}, Status: "resolved"}, start.Add(time.Minute))
+	m.filter(Alert{Labels: map[string]string{"check": "b"}, Status: "resolved"}, start.Add(time.Minute))
+	got := describeAlerts(m.filter(Alert{Labels: map[string]string{"check": "c"}, Status: "resolved"}, start.Add(time.Minute)))
+	if want := []string{"resolved MassOutage"}; !slices.Equal(got, want) {
+		t.Fatalf("recovery of c delivered %q, want %q", got, want)
+	}
+
+	waitFor(t, "the release timer", func() bool { return fake.waiting() > 0 })
+	select {
+	case al := <-released:
+		t.Fatalf("released %s before the window passed", describeAlerts([]Alert{al}))
+	default:
+	}
+	fake.advance(time.Minute)
+	select {
+	case al := <-released:
+		if got := describeAlerts([]Alert{al}); !slices.Equal(got, []string{"firing d"}) {
+			t.Errorf("released %q, want firing d", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing released after the window")
+	}
+}
+
+func describeAlerts(alerts []Alert) []string {
+	var out []string
+	for _, al := range alerts {
+		name := al.Labels["check"]
+		if name == "" {
+			name = al.Labels["alertname"]
+		}
+		out = append(out, al.Status+" "+name)
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package goping
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+const defaultMetricsNamespace = "goping"
+
+var (
+	metricNamespaceRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	metricLabelRe     = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// MetricsConfig controls how series are exported, so several goping
+// deployments can share one Prometheus without relabelling.
+type MetricsConfig struct {
+	// Namespace replaces the goping_ prefix of goping's own metrics.
+	Namespace string `yaml:"namespace,omitempty"`
+	// Labels are added to every exported series.
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// metricsGatherer rewrites what the default registry gathers according to a
+// MetricsConfig. Metrics are still declared and registered with their goping_
+// names; only the exposition changes.
+type metricsGatherer struct {
+	gatherer  prometheus.Gatherer
+	namespace string
+	labels    []*dto.LabelPair
+}
+
+func newMetricsGatherer(cfg MetricsConfig, g prometheus.Gatherer) (prometheus.Gatherer, error) {
+	namespace := strings.TrimSuffix(cfg.Namespace, "_")
+	if namespace == "" {
+		namespace = defaultMetricsNamespace
+	}
+	if !metricNamespaceRe.MatchString(namespace) {
+		return nil, fmt.Errorf("metrics.namespace: %q is not a valid metric name prefix", cfg.Namespace)
+	}
+
+	m := &metricsGatherer{gatherer: g, namespace: namespace}
+	for name, value := range cfg.Labels {
+		if !metricLabelRe.MatchString(name) || strings.HasPrefix(name, "__") {
+			return nil, fmt.Errorf("metrics.labels: %q is not a valid label name", name)
+		}
+		m.labels = append(m.labels, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+	}
+	slices.SortFunc(m.labels, func(a, b *dto.LabelPair) int { return strings.Compare(a.GetName(), b.GetName()) })
+
+	if namespace == defaultMetricsNamespace && len(m.labels) == 0 {
+		return g, nil
+	}
+	return m, nil
+}
+
+// Gather renames and labels the gathered families. A series that already has
+// one of the configured labels keeps its own value.
+func (m *metricsGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := m.gatherer.Gather()
+	for _, mf := range families {
+		if name, ok := strings.CutPrefix(mf.GetName(), defaultMetricsNamespace+"_"); ok {
+			mf.Name = proto.String(m.namespace + "_" + name)
+		}
+		if len(m.labels) == 0 {
+			continue
+		}
+		for _, metric := range mf.Metric {
+			for _, l := range m.labels {
+				if !slices.ContainsFunc(metric.Label, func(p *dto.LabelPair) bool { return p.GetName() == l.GetName() }) {
+					metric.Label = append(metric.Label, l)
+				}
+			}
+			slices.SortFunc(metric.Label, func(a, b *dto.LabelPair) int { return strings.Compare(a.GetName(), b.GetName()) })
+		}
+	}
+	return families, err
+}
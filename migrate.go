@@ -0,0 +1,182 @@
+package goping
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// currentConfigVersion is the schema version written by migrate-config.
+// Configs without a version field are version 1.
+const currentConfigVersion = 2
+
+// configMigration upgrades a config document from one version to the next,
+// returning a note for every field it changed.
+type configMigration struct {
+	from  int
+	apply func(doc *yaml.Node) []string
+}
+
+var configMigrations = []configMigration{
+	{from: 1, apply: migrateV1ToV2},
+}
+
+// migrateV1ToV2 renames the top-level checks list to targets, matching the
+// API and dashboard.
+func migrateV1ToV2(doc *yaml.Node) []string {
+	if renameKey(doc, "checks", "targets") {
+		return []string{"checks: renamed to targets"}
+	}
+	return nil
+}
+
+func mappingValue(m *yaml.Node, key string) (*yaml.Node, int) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1], i
+		}
+	}
+	return nil, -1
+}
+
+func renameKey(m *yaml.Node, from, to string) bool {
+	_, i := mappingValue(m, from)
+	if i < 0 {
+		return false
+	}
+	if _, j := mappingValue(m, to); j >= 0 {
+		return false
+	}
+	m.Content[i].Value = to
+	return true
+}
+
+// migrateConfig upgrades a parsed config document in place to the current
+// version. It returns the version it started from and notes on what changed.
+func migrateConfig(root *yaml.Node) (int, []string, error) {
+	if root.Kind == 0 {
+		// Empty file
+		return currentConfigVersion, nil, nil
+	}
+
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return 0, nil, fmt.Errorf("config must be a mapping")
+	}
+
+	version := 1
+	versionNode, _ := mappingValue(doc, "version")
+	if versionNode != nil {
+		v, err := strconv.Atoi(versionNode.Value)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid version %q", versionNode.Value)
+		}
+		version = v
+	}
+	if version > currentConfigVersion {
+		return 0, nil, fmt.Errorf("config version %d is newer than this goping supports (%d)", version, currentConfigVersion)
+	}
+
+	from := version
+	var notes []string
+	for _, m := range configMigrations {
+		if m.from == version {
+			notes = append(notes, m.apply(doc)...)
+			version++
+		}
+	}
+
+	if versionNode == nil {
+		versionNode = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int"}
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: "version"}
+		if len(doc.Content) > 0 {
+			// Keep a leading file comment at the top.
+			key.HeadComment, doc.Content[0].HeadComment = doc.Content[0].HeadComment, ""
+		}
+		doc.Content = append([]*yaml.Node{key, versionNode}, doc.Content...)
+	}
+	versionNode.Value = strconv.Itoa(version)
+
+	return from, notes, nil
+}
+
+// runMigrateConfig implements `goping migrate-config`, which rewrites a
+// config file in the current schema, keeping comments, and reports what
+// changed. The original is kept with a .bak suffix.
+func runMigrateConfig(args []string) int {
+	fs := flag.NewFlagSet("migrate-config", flag.ExitOnError)
+	configPath := fs.String("config", "goping.yaml", "path to the config file to migrate")
+	outPath := fs.String("o", "", "write the result here instead of replacing the config file")
+	dryRun := fs.Bool("dry-run", false, "print the result instead of writing it")
+	fs.Parse(args)
+
+	data, err := os.ReadFile(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to parse %s: %v\n", *configPath, err)
+		return 1
+	}
+
+	from, notes, err := migrateConfig(&root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+		return 1
+	}
+	if from == currentConfigVersion {
+		fmt.Fprintf(os.Stderr, "%s is already at version %d\n", *configPath, currentConfigVersion)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "Migrating %s from version %d to %d\n", *configPath, from, currentConfigVersion)
+	for _, n := range notes {
+		fmt.Fprintf(os.Stderr, "  deprecated %s\n", n)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	enc.Close()
+
+	// Make sure the result loads before writing it.
+	if _, err := parseConfig(buf.Bytes()); err != nil {
+		fmt.Fprintf(os.Stderr, "migrated config is invalid: %v\n", err)
+		return 1
+	}
+
+	if *dryRun {
+		os.Stdout.Write(buf.Bytes())
+		return 0
+	}
+
+	out := *outPath
+	if out == "" {
+		out = *configPath
+		if err := os.WriteFile(*configPath+".bak", data, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", out)
+	return 0
+}
@@ -0,0 +1,114 @@
+package goping
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		from    int
+		notes   []string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "v1",
+			in:    "checks:\n  - name: web\n    type: http\n",
+			from:  1,
+			notes: []string{"checks: renamed to targets"},
+			want:  "version: 2\ntargets:\n  - name: web\n    type: http\n",
+		},
+		{
+			name:  "explicit v1",
+			in:    "version: 1\nchecks: []\n",
+			from:  1,
+			notes: []string{"checks: renamed to targets"},
+			want:  "version: 2\ntargets: []\n",
+		},
+		{
+			name: "v1 already using targets",
+			in:   "targets: []\n",
+			from: 1,
+			want: "version: 2\ntargets: []\n",
+		},
+		{
+			// Renaming would lose one of the lists, so both are left for
+			// the config loader to reject.
+			name: "both lists",
+			in:   "checks: []\ntargets: []\n",
+			from: 1,
+			want: "version: 2\nchecks: []\ntargets: []\n",
+		},
+		{
+			name: "current",
+			in:   "version: 2\ntargets: []\n",
+			from: 2,
+			want: "version: 2\ntargets: []\n",
+		},
+		{
+			name:  "comments kept",
+			in:    "# goping config\n\n# what to check\nchecks: [] # none yet\n",
+			from:  1,
+			notes: []string{"checks: renamed to targets"},
+			want:  "# goping config\n\n# what to check\nversion: 2\ntargets: [] # none yet\n",
+		},
+		{
+			name: "empty",
+			in:   "",
+			from: currentConfigVersion,
+			want: "",
+		},
+		{name: "newer", in: "version: 3\n", wantErr: "config version 3 is newer than this goping supports (2)"},
+		{name: "bad version", in: "version: two\n", wantErr: `invalid version "two"`},
+		{name: "not a mapping", in: "- a\n- b\n", wantErr: "config must be a mapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var root yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.in), &root); err != nil {
+				t.Fatal(err)
+			}
+
+			from, notes, err := migrateConfig(&root)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("migrateConfig = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("migrateConfig: %v", err)
+			}
+			if from != tt.from {
+				t.Errorf("from = %d, want %d", from, tt.from)
+			}
+			if !slices.Equal(notes, tt.notes) {
+				t.Errorf("notes = %q, want %q", notes, tt.notes)
+			}
+
+			if root.Kind == 0 {
+				if tt.want != "" {
+					t.Errorf("nothing migrated, want %q", tt.want)
+				}
+				return
+			}
+			var buf bytes.Buffer
+			enc := yaml.NewEncoder(&buf)
+			enc.SetIndent(2)
+			if err := enc.Encode(&root); err != nil {
+				t.Fatal(err)
+			}
+			enc.Close()
+			if got := buf.String(); got != tt.want {
+				t.Errorf("migrated to\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+package goping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const alertQueueSize = 100
+
+var (
+	alertsSilenced = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_alerts_silenced_total",
+			Help: "Total number of alerts suppressed by a silence",
+		},
+		[]string{"check"},
+	)
+
+	notificationsSent = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_notifications_sent_total",
+			Help: "Notifications delivered successfully",
+		},
+		[]string{"notifier"},
+	)
+
+	notificationsFailed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_notifications_failed_total",
+			Help: "Notifications that could not be delivered after all retries",
+		},
+		[]string{"notifier"},
+	)
+
+	notificationsRetried = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_notifications_retried_total",
+			Help: "Retried notification delivery attempts",
+		},
+		[]string{"notifier"},
+	)
+
+	notificationsDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_notifications_dropped_total",
+			Help: "Notifications dropped because the notifier's queue was full",
+		},
+		[]string{"notifier"},
+	)
+
+	notificationsQueued = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goping_notifications_queued",
+			Help: "Notifications waiting to be delivered",
+		},
+		[]string{"notifier"},
+	)
+
+	notificationLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "goping_notification_latency_seconds",
+			Help:    "Time from an alert firing until its notification was delivered, including queueing and retries",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+		},
+		[]string{"notifier"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(alertsSilenced)
+	prometheus.MustRegister(notificationsSent)
+	prometheus.MustRegister(notificationsFailed)
+	prometheus.MustRegister(notificationsRetried)
+	prometheus.MustRegister(notificationsDropped)
+	prometheus.MustRegister(notificationsQueued)
+	prometheus.MustRegister(notificationLatency)
+}
+
+var alerts *alerter
+
+type NotifierConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	URL  string `yaml:"url"`
+
+	// Template, if set, renders the request body, or the exec notifier's
+	// input, from the alert instead of sending it as JSON.
+	Template    string `yaml:"template"`
+	ContentType string `yaml:"content_type"`
+
+	// exec
+	Command []string      `yaml:"command"`
+	Timeout time.Duration `yaml:"timeout"`
+
+	RateLimit *NotifierRateLimitConfig `yaml:"rate_limit"`
+}
+
+// Notifier delivers alerts to one destination. Each notifier has its own
+// queue and goroutine, so Send is never called concurrently; it should give
+// up when ctx is done.
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, a Alert) error
+}
+
+// NotifierFactory creates a notifier registered with RegisterNotifier from
+// its entry in the config. Fields the type doesn't use are left for it to
+// ignore or reject.
+type NotifierFactory func(cfg NotifierConfig) (Notifier, error)
+
+var notifierTypes = map[string]NotifierFactory{}
+
+// RegisterNotifier makes a notifier type available to configs. Like the
+// built-in ones, its notifiers get their own queue, metrics and rate limit,
+// and should use the config's name as their Name; retrying a failed Send is
+// up to the notifier. RegisterNotifier is called before Main, typically from
+// init, and panics if typ is already taken.
+func RegisterNotifier(typ string, f NotifierFactory) {
+	switch typ {
+	case "", "webhook", "exec":
+		panic(fmt.Sprintf("goping: notifier type %q is built in", typ))
+	}
+	if _, ok := notifierTypes[typ]; ok {
+		panic(fmt.Sprintf("goping: notifier type %q registered twice", typ))
+	}
+	notifierTypes[typ] = f
+}
+
+// Alert is the payload delivered to notifiers when a check changes state.
+type Alert struct {
+	Labels   map[string]string `json:"labels"`
+	Status   string            `json:"status"`
+	Summary  string            `json:"summary"`
+	StartsAt time.Time         `json:"startsAt"`
+	EndsAt   time.Time         `json:"endsAt,omitzero"`
+}
+
+// renderAlert returns the alert as JSON, or rendered with tmpl if set.
+func renderAlert(tmpl *template.Template, a Alert) ([]byte, error) {
+	if tmpl == nil {
+		return json.Marshal(a)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, a); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseNotifierTemplate parses the template of a notifier, if it has one.
+func parseNotifierTemplate(c NotifierConfig) (*template.Template, error) {
+	if c.Template == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(c.Name).Funcs(templateFuncs).Option("missingkey=zero").Parse(c.Template)
+	if err != nil {
+		return nil, fmt.Errorf("notifier %q: %w", c.Name, err)
+	}
+	return tmpl, nil
+}
+
+type webhookNotifier struct {
+	name        string
+	url         string
+	client      *retryablehttp.Client
+	tmpl        *template.Template
+	contentType string
+}
+
+func newWebhookNotifier(c NotifierConfig) (*webhookNotifier, error) {
+	if c.URL == "" {
+		return nil, fmt.Errorf("notifier %q: url is required", c.Name)
+	}
+
+	tmpl, err := parseNotifierTemplate(c)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &webhookNotifier{
+		name:        c.Name,
+		url:         c.URL,
+		client:      newNotifierClient(c.Name),
+		tmpl:        tmpl,
+		contentType: c.ContentType,
+	}
+	if n.contentType == "" && tmpl != nil {
+		n.contentType = "text/plain; charset=utf-8"
+	}
+	if n.contentType == "" {
+		n.contentType = "application/json"
+	}
+	return n, nil
+}
+
+// newNotifierClient returns a client with the same retry policy as
+// retryClient that counts retries against the named notifier.
+func newNotifierClient(name string) *retryablehttp.Client {
+	c := retryablehttp.NewClient()
+	c.RetryWaitMin = retryClient.RetryWaitMin
+	c.RetryWaitMax = retryClient.RetryWaitMax
+	c.RetryMax = retryClient.RetryMax
+	c.Backoff = retryClient.Backoff
+	c.CheckRetry = retryClient.CheckRetry
+	c.RequestLogHook = func(_ retryablehttp.Logger, _ *http.Request, attempt int) {
+		if attempt > 0 {
+			notificationsRetried.WithLabelValues(name).Inc()
+		}
+	}
+	return c
+}
+
+func (n *webhookNotifier) Name() string {
+	return n.name
+}
+
+// withoutRetries returns a copy of n that makes a single attempt, so the
+// notifier self-test shows a failure straight away.
+func (n *webhookNotifier) withoutRetries() Notifier {
+	c := *n
+	c.client = newNotifierClient(n.name)
+	c.client.RetryMax = 0
+	c.client.Logger = nil
+	return &c
+}
+
+func (n *webhookNotifier) Send(ctx context.Context, a Alert) error {
+	body, err := renderAlert(n.tmpl, a)
+	if err != nil {
+		return err
+	}
+
+	r, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", n.contentType)
+
+	resp, err := n.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// queuedAlert is an alert waiting for delivery, with the time it fired.
+type queuedAlert struct {
+	Alert
+	fired time.Time
+}
+
+// channel delivers alerts to one notifier from its own queue, so a slow or
+// failing notifier doesn't hold up the others.
+type channel struct {
+	notifier Notifier
+	queue    chan queuedAlert
+	limit    *notifyLimit
+}
+
+type alerter struct {
+	channels []*channel
+	outage   *massOutage
+}
+
+func newAlerter(cfgs []NotifierConfig, outage MassOutageConfig) (*alerter, error) {
+	a := &alerter{}
+
+	var err error
+	if a.outage, err = newMassOutage(outage); err != nil {
+		return nil, err
+	}
+	if a.outage != nil {
+		a.outage.release = func(al Alert) {
+			a.enqueue(queuedAlert{Alert: al, fired: timeSource.Now()})
+		}
+	}
+
+	for i, c := range cfgs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("notifier %d: name is required", i)
+		}
+		var n Notifier
+		var err error
+		switch c.Type {
+		case "webhook":
+			n, err = newWebhookNotifier(c)
+		case "exec":
+			n, err = newExecNotifier(c)
+		default:
+			f, ok := notifierTypes[c.Type]
+			if !ok {
+				err = fmt.Errorf("notifier %q: unknown type %q", c.Name, c.Type)
+				break
+			}
+			if n, err = f(c); err != nil {
+				err = fmt.Errorf("notifier %q: %w", c.Name, err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		limit, err := newNotifyLimit(c.Name, c.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		a.addChannel(n, limit)
+	}
+	for _, n := range extraNotifiers {
+		a.addChannel(n, nil)
+	}
+
+	return a, nil
+}
+
+func (a *alerter) addChannel(n Notifier, limit *notifyLimit) {
+	a.channels = append(a.channels, &channel{notifier: n, queue: make(chan queuedAlert, alertQueueSize), limit: limit})
+
+	// Export every series from the start so rates can be alerted on.
+	name := n.Name()
+	notificationsSent.WithLabelValues(name)
+	notificationsFailed.WithLabelValues(name)
+	notificationsRetried.WithLabelValues(name)
+	notificationsDropped.WithLabelValues(name)
+	notificationsRateLimited.WithLabelValues(name)
+	notificationsQueued.WithLabelValues(name).Set(0)
+}
+
+// fire queues an alert for delivery unless it is held back for a deploy,
+// silenced or collapsed into a mass outage. It never blocks the caller;
+// alerts are dropped for notifiers whose queue is full.
+func (a *alerter) fire(al Alert) {
+	now := timeSource.Now()
+	al, ok := deploys.filter(al, now)
+	if !ok {
+		return
+	}
+	if s := silences.matching(al.Labels, now); s != nil {
+		logger.Info("Alert silenced", "labels", al.Labels, "status", al.Status, "silence", s.ID)
+		alertsSilenced.WithLabelValues(al.Labels["check"]).Inc()
+		return
+	}
+
+	for _, out := range a.outage.filter(al, now) {
+		a.enqueue(queuedAlert{Alert: out, fired: now})
+	}
+}
+
+func (a *alerter) enqueue(q queuedAlert) {
+	al := q.Alert
+	for _, ch := range a.channels {
+		name := ch.notifier.Name()
+		select {
+		case ch.queue <- q:
+			notificationsQueued.WithLabelValues(name).Set(float64(len(ch.queue)))
+		default:
+			notificationsDropped.WithLabelValues(name).Inc()
+			runStats.notificationsDropped.Add(1)
+			logger.Error("Alert queue full, dropping alert", "notifier", name, "labels", al.Labels, "status", al.Status)
+		}
+	}
+}
+
+func (a *alerter) run(ctx context.Context) {
+	for _, ch := range a.channels {
+		go ch.run(ctx)
+	}
+	<-ctx.Done()
+}
+
+func (ch *channel) run(ctx context.Context) {
+	name := ch.notifier.Name()
+
+	// digest fires when a rate-limited channel has room again for the
+	// digest of the alerts it held back.
+	var digest <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case q := <-ch.queue:
+			notificationsQueued.WithLabelValues(name).Set(float64(len(ch.queue)))
+			if ch.limit == nil || len(ch.limit.overflow) == 0 && ch.limit.allow(timeSource.Now()) {
+				ch.deliver(ctx, q)
+				continue
+			}
+			if len(ch.limit.overflow) == 0 {
+				digest = timeSource.After(ch.limit.next().Sub(timeSource.Now()))
+				logger.Warn("Notifier rate limit reached, holding back alerts for a digest", "notifier", name, "until", ch.limit.next())
+			}
+			ch.limit.hold(q)
+			notificationsRateLimited.WithLabelValues(name).Inc()
+		case <-digest:
+			if !ch.limit.allow(timeSource.Now()) {
+				digest = timeSource.After(ch.limit.next().Sub(timeSource.Now()))
+				continue
+			}
+			digest = nil
+			ch.deliver(ctx, ch.limit.digest())
+		}
+	}
+}
+
+func (ch *channel) deliver(ctx context.Context, q queuedAlert) {
+	n := ch.notifier
+	name := n.Name()
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic("notifier", r, "notifier", name)
+			notificationsFailed.WithLabelValues(name).Inc()
+			runStats.notificationsFailed.Add(1)
+		}
+	}()
+
+	if err := n.Send(ctx, q.Alert); err != nil {
+		notificationsFailed.WithLabelValues(name).Inc()
+		runStats.notificationsFailed.Add(1)
+		logger.Error("Failed to send notification", "notifier", name, "error", err)
+		return
+	}
+	notificationsSent.WithLabelValues(name).Inc()
+	runStats.notificationsSent.Add(1)
+	notificationLatency.WithLabelValues(name).Observe(timeSource.Now().Sub(q.fired).Seconds())
+	logger.Info("Notification sent", "notifier", name, "status", q.Status, "check", q.Labels["check"])
+}
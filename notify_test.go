@@ -0,0 +1,57 @@
+package goping
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegisterNotifier(t *testing.T) {
+	sent := make(recordingNotifier, 1)
+	var got NotifierConfig
+	RegisterNotifier("recording", func(cfg NotifierConfig) (Notifier, error) {
+		got = cfg
+		if cfg.URL == "" {
+			return nil, errors.New("url is required")
+		}
+		return sent, nil
+	})
+	t.Cleanup(func() { delete(notifierTypes, "recording") })
+
+	a, err := newAlerter([]NotifierConfig{{Name: "test", Type: "recording", URL: "topic"}}, MassOutageConfig{})
+	if err != nil {
+		t.Fatalf("newAlerter: %v", err)
+	}
+	if got.Name != "test" || got.URL != "topic" {
+		t.Errorf("factory got %+v, want the notifier's config", got)
+	}
+	if len(a.channels) != 1 || a.channels[0].notifier != Notifier(sent) {
+		t.Fatalf("alerter has channels %v, want one for the registered notifier", a.channels)
+	}
+
+	_, err = newAlerter([]NotifierConfig{{Name: "bad", Type: "recording"}}, MassOutageConfig{})
+	if err == nil || !strings.Contains(err.Error(), `notifier "bad": url is required`) {
+		t.Errorf("newAlerter with a failing factory = %v, want its error", err)
+	}
+	_, err = newAlerter([]NotifierConfig{{Name: "x", Type: "carrier-pigeon"}}, MassOutageConfig{})
+	if err == nil || !strings.Contains(err.Error(), `unknown type "carrier-pigeon"`) {
+		t.Errorf("newAlerter with an unregistered type = %v, want an unknown type error", err)
+	}
+}
+
+func TestRegisterNotifierTaken(t *testing.T) {
+	f := func(NotifierConfig) (Notifier, error) { return nil, nil }
+	RegisterNotifier("recording", f)
+	t.Cleanup(func() { delete(notifierTypes, "recording") })
+
+	for _, typ := range []string{"recording", "webhook", "exec", ""} {
+		t.Run(typ, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterNotifier(%q) didn't panic", typ)
+				}
+			}()
+			RegisterNotifier(typ, f)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package goping
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultExecTimeout = 30 * time.Second
+
+	// execStderrLimit caps how much of a failed command's stderr ends up in
+	// the error.
+	execStderrLimit = 512
+)
+
+// execNotifier runs a command for every alert with the alert as JSON, or
+// rendered with the template, on stdin. A non-zero exit is a failed send.
+type execNotifier struct {
+	name    string
+	command []string
+	timeout time.Duration
+	tmpl    *template.Template
+}
+
+func newExecNotifier(c NotifierConfig) (*execNotifier, error) {
+	if len(c.Command) == 0 {
+		return nil, fmt.Errorf("notifier %q: command is required", c.Name)
+	}
+	if _, err := exec.LookPath(c.Command[0]); err != nil {
+		return nil, fmt.Errorf("notifier %q: %w", c.Name, err)
+	}
+
+	tmpl, err := parseNotifierTemplate(c)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &execNotifier{name: c.Name, command: c.Command, timeout: c.Timeout, tmpl: tmpl}
+	if n.timeout <= 0 {
+		n.timeout = defaultExecTimeout
+	}
+	return n, nil
+}
+
+func (n *execNotifier) Name() string {
+	return n.name
+}
+
+func (n *execNotifier) Send(ctx context.Context, a Alert) error {
+	input, err := renderAlert(n.tmpl, a)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, n.command[0], n.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = &stderr
+	cmd.Env = append(cmd.Environ(),
+		"GOPING_ALERT_STATUS="+a.Status,
+		"GOPING_ALERT_CHECK="+a.Labels["check"],
+	)
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("command timed out after %s", n.timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > execStderrLimit {
+			msg = msg[len(msg)-execStderrLimit:]
+		}
+		if msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
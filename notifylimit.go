@@ -0,0 +1,102 @@
+package goping
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// digestMaxSummaries caps how many held-back alerts a digest lists; the rest
+// are only counted.
+const digestMaxSummaries = 20
+
+var notificationsRateLimited = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goping_notifications_rate_limited_total",
+		Help: "Alerts held back by a notifier's rate limit and summarised in a digest",
+	},
+	[]string{"notifier"},
+)
+
+func init() {
+	prometheus.MustRegister(notificationsRateLimited)
+}
+
+// NotifierRateLimitConfig allows at most Max notifications per Per. Alerts
+// over the limit are summarised in one digest sent when the limit allows.
+type NotifierRateLimitConfig struct {
+	Max int           `yaml:"max"`
+	Per time.Duration `yaml:"per"`
+}
+
+// notifyLimit is a sliding window of a channel's recent sends, plus the
+// alerts held back since the window filled up. It is only used from the
+// channel's goroutine.
+type notifyLimit struct {
+	max int
+	per time.Duration
+
+	sent     []time.Time
+	overflow []queuedAlert
+}
+
+func newNotifyLimit(name string, cfg *NotifierRateLimitConfig) (*notifyLimit, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Max < 1 || cfg.Per <= 0 {
+		return nil, fmt.Errorf("notifier %q: rate_limit needs max of at least 1 and a positive per", name)
+	}
+	return &notifyLimit{max: cfg.Max, per: cfg.Per}, nil
+}
+
+// allow reports whether a notification may be sent now, and records it if so.
+func (l *notifyLimit) allow(now time.Time) bool {
+	cutoff := now.Add(-l.per)
+	i := 0
+	for i < len(l.sent) && !l.sent[i].After(cutoff) {
+		i++
+	}
+	l.sent = l.sent[i:]
+
+	if len(l.sent) >= l.max {
+		return false
+	}
+	l.sent = append(l.sent, now)
+	return true
+}
+
+// next returns when the window will next have room.
+func (l *notifyLimit) next() time.Time {
+	return l.sent[0].Add(l.per)
+}
+
+func (l *notifyLimit) hold(q queuedAlert) {
+	l.overflow = append(l.overflow, q)
+}
+
+// digest summarises the held-back alerts in one alert and clears them.
+func (l *notifyLimit) digest() queuedAlert {
+	held := l.overflow
+	l.overflow = nil
+
+	lines := make([]string, 0, min(len(held), digestMaxSummaries))
+	for _, q := range held[:min(len(held), digestMaxSummaries)] {
+		lines = append(lines, q.Summary)
+	}
+	if extra := len(held) - len(lines); extra > 0 {
+		lines = append(lines, fmt.Sprintf("and %d more", extra))
+	}
+
+	return queuedAlert{
+		Alert: Alert{
+			Labels:   map[string]string{"alertname": "NotificationDigest"},
+			Status:   "digest",
+			Summary:  fmt.Sprintf("%d alerts held back by the rate limit of %d per %s: %s", len(held), l.max, l.per, strings.Join(lines, "; ")),
+			StartsAt: held[0].fired,
+		},
+		fired: held[0].fired,
+	}
+}
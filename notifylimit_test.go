@@ -0,0 +1,137 @@
+package goping
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifyLimitAllow(t *testing.T) {
+	tests := []struct {
+		name  string
+		max   int
+		per   time.Duration
+		sends []time.Duration // since the first send
+		want  []bool
+	}{
+		{
+			name:  "under the limit",
+			max:   3,
+			per:   time.Minute,
+			sends: []time.Duration{0, time.Second, 2 * time.Second},
+			want:  []bool{true, true, true},
+		},
+		{
+			name:  "over the limit",
+			max:   2,
+			per:   time.Minute,
+			sends: []time.Duration{0, time.Second, 2 * time.Second, 59 * time.Second},
+			want:  []bool{true, true, false, false},
+		},
+		{
+			// The first send leaves the window exactly per after it was made.
+			name:  "window slides",
+			max:   2,
+			per:   time.Minute,
+			sends: []time.Duration{0, 30 * time.Second, 59 * time.Second, time.Minute, 61 * time.Second},
+			want:  []bool{true, true, false, true, false},
+		},
+		{
+			name:  "refused sends don't count",
+			max:   1,
+			per:   time.Minute,
+			sends: []time.Duration{0, 10 * time.Second, 20 * time.Second, time.Minute, 2 * time.Minute},
+			want:  []bool{true, false, false, true, true},
+		},
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := newNotifyLimit("test", &NotifierRateLimitConfig{Max: tt.max, Per: tt.per})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []bool
+			for _, d := range tt.sends {
+				got = append(got, l.allow(start.Add(d)))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("allow at %v = %v, want %v", tt.sends, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyLimitNext(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l, err := newNotifyLimit("test", &NotifierRateLimitConfig{Max: 2, Per: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.allow(start)
+	l.allow(start.Add(10 * time.Second))
+	if got, want := l.next(), start.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("next = %s, want %s", got, want)
+	}
+}
+
+func TestNewNotifyLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *NotifierRateLimitConfig
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "unset", cfg: nil, wantNil: true},
+		{name: "valid", cfg: &NotifierRateLimitConfig{Max: 1, Per: time.Second}},
+		{name: "no max", cfg: &NotifierRateLimitConfig{Per: time.Second}, wantErr: true},
+		{name: "no per", cfg: &NotifierRateLimitConfig{Max: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := newNotifyLimit("test", tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newNotifyLimit error = %v, want an error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && (l == nil) != tt.wantNil {
+				t.Errorf("newNotifyLimit = %v, want nil %t", l, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestNotifyLimitDigest(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		held int
+		want string
+	}{
+		{1, "1 alerts held back by the rate limit of 1 per 1m0s: Check c0 is down"},
+		{3, "3 alerts held back by the rate limit of 1 per 1m0s: Check c0 is down; Check c1 is down; Check c2 is down"},
+		{digestMaxSummaries + 5, "; Check c19 is down; and 5 more"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.held), func(t *testing.T) {
+			l := &notifyLimit{max: 1, per: time.Minute}
+			for i := range tt.held {
+				l.hold(queuedAlert{
+					Alert: Alert{Status: "firing", Summary: fmt.Sprintf("Check c%d is down", i)},
+					fired: start.Add(time.Duration(i) * time.Second),
+				})
+			}
+
+			d := l.digest()
+			if d.Status != "digest" || !strings.Contains(d.Summary, tt.want) {
+				t.Errorf("digest = %s %q, want digest containing %q", d.Status, d.Summary, tt.want)
+			}
+			if !d.StartsAt.Equal(start) || !d.fired.Equal(start) {
+				t.Errorf("digest starts at %s, fired %s, want both %s", d.StartsAt, d.fired, start)
+			}
+			if len(l.overflow) != 0 {
+				t.Errorf("%d alerts still held after the digest", len(l.overflow))
+			}
+		})
+	}
+}
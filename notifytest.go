@@ -0,0 +1,108 @@
+package goping
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+const notifyTestTimeout = 30 * time.Second
+
+// notifyTestResult is the outcome of sending a test alert to one notifier.
+type notifyTestResult struct {
+	notifier string
+	err      error
+}
+
+// testAlert is sent to check notifier configuration. Its status is neither
+// firing nor resolved, so receivers that open incidents can ignore it.
+func testAlert() Alert {
+	return Alert{
+		Labels:   map[string]string{"alertname": "GopingTest"},
+		Status:   "test",
+		Summary:  "Test notification from goping",
+		StartsAt: timeSource.Now(),
+	}
+}
+
+// retryingNotifier is implemented by notifiers that retry failed sends.
+type retryingNotifier interface {
+	withoutRetries() Notifier
+}
+
+// test sends a test alert to every notifier directly, bypassing queues,
+// silences and mass outage handling, and reports how each went. Failures are
+// not retried, so a broken notifier shows its actual error quickly.
+func (a *alerter) test(ctx context.Context) []notifyTestResult {
+	results := make([]notifyTestResult, len(a.channels))
+	done := make(chan struct{})
+	for i, ch := range a.channels {
+		n := ch.notifier
+		if r, ok := n.(retryingNotifier); ok {
+			n = r.withoutRetries()
+		}
+		go func() {
+			defer func() { done <- struct{}{} }()
+			results[i] = notifyTestResult{notifier: n.Name(), err: n.Send(ctx, testAlert())}
+		}()
+	}
+	for range a.channels {
+		<-done
+	}
+	return results
+}
+
+// testNotifiers logs the outcome of a startup self-test of the notifiers.
+func testNotifiers(ctx context.Context, a *alerter) {
+	ctx, cancel := context.WithTimeout(ctx, notifyTestTimeout)
+	defer cancel()
+
+	for _, r := range a.test(ctx) {
+		if r.err != nil {
+			logger.Error("Notifier self-test failed", "notifier", r.notifier, "error", r.err)
+			continue
+		}
+		logger.Info("Notifier self-test succeeded", "notifier", r.notifier)
+	}
+}
+
+func runNotifyTest(args []string) int {
+	fs := flag.NewFlagSet("notify-test", flag.ExitOnError)
+	configPath := fs.String("config", "goping.yaml", "path to the config file defining the notifiers")
+	timeout := fs.Duration("timeout", notifyTestTimeout, "how long to wait for all notifiers")
+	fs.Parse(args)
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	a, err := newAlerter(cfg.Notifiers, cfg.MassOutage)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(a.channels) == 0 {
+		fmt.Fprintf(os.Stderr, "%s defines no notifiers\n", *configPath)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	failed := 0
+	for _, r := range a.test(ctx) {
+		if r.err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", r.notifier, r.err)
+			continue
+		}
+		fmt.Printf("ok   %s\n", r.notifier)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
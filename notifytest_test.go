@@ -0,0 +1,53 @@
+package goping
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifierWithoutRetries(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	n, err := newWebhookNotifier(NotifierConfig{Name: "webhook-test", URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.client.RetryMax = 2
+	n.client.RetryWaitMin = time.Millisecond
+	n.client.RetryWaitMax = time.Millisecond
+	n.client.Logger = nil
+
+	tests := []struct {
+		name string
+		n    Notifier
+		want int32
+	}{
+		{"retrying", n, 3},
+		{"without retries", n.withoutRetries(), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			if err := tt.n.Send(context.Background(), testAlert()); err == nil {
+				t.Error("Send succeeded against a failing webhook")
+			}
+			if got := requests.Load(); got != tt.want {
+				t.Errorf("%d requests, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// The copy leaves the original retrying.
+	if n.client.RetryMax != 2 {
+		t.Errorf("original RetryMax = %d, want 2", n.client.RetryMax)
+	}
+}
@@ -0,0 +1,49 @@
+package goping
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var panicsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goping_panics_total",
+		Help: "Panics recovered, by the component they happened in",
+	},
+	[]string{"component"},
+)
+
+func init() {
+	prometheus.MustRegister(panicsTotal)
+}
+
+// reportPanic logs a recovered panic with its stack and counts it. It must
+// be called from the deferred function that recovered.
+func reportPanic(component string, r any, attrs ...any) {
+	panicsTotal.WithLabelValues(component).Inc()
+	attrs = append(attrs, "component", component, "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+	logger.Error("Recovered from panic", attrs...)
+}
+
+// recoverPanic recovers and reports a panic when deferred directly, letting
+// the goroutine carry on with its next iteration.
+func recoverPanic(component string, attrs ...any) {
+	if r := recover(); r != nil {
+		reportPanic(component, r, attrs...)
+	}
+}
+
+// safeCheck runs c, turning a panic into a failed check so one broken check
+// type can't take down the daemon.
+func safeCheck(ctx context.Context, name string, c checker) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic("check", r, "check", name)
+			err = newCheckError(categoryPanic, "", false, fmt.Errorf("check panicked: %v", r))
+		}
+	}()
+	return c.check(ctx)
+}
@@ -0,0 +1,69 @@
+package goping
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var checkPaused = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "goping_check_paused",
+		Help: "Whether scheduled runs of a check are paused (1) or not (0)",
+	},
+	[]string{"check"},
+)
+
+func init() {
+	prometheus.MustRegister(checkPaused)
+}
+
+// pause stops scheduled runs of the target. A positive d resumes it
+// automatically after that long. State and history are kept while paused.
+func (t *target) pause(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.paused = true
+	t.pausedUntil = time.Time{}
+	if d > 0 {
+		t.pausedUntil = timeSource.Now().Add(d)
+	}
+	checkPaused.WithLabelValues(t.cfg.Name).Set(1)
+
+	if t.pausedUntil.IsZero() {
+		logger.Info("Check paused", "check", t.cfg.Name)
+	} else {
+		logger.Info("Check paused", "check", t.cfg.Name, "until", t.pausedUntil)
+	}
+}
+
+func (t *target) resume() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.resumeLocked()
+}
+
+func (t *target) resumeLocked() {
+	if !t.paused {
+		return
+	}
+	t.paused = false
+	t.pausedUntil = time.Time{}
+	t.resumedAt = timeSource.Now()
+	checkPaused.WithLabelValues(t.cfg.Name).Set(0)
+	logger.Info("Check resumed", "check", t.cfg.Name)
+}
+
+// isPaused reports whether the target is paused, resuming it first if its
+// pause has run out.
+func (t *target) isPaused(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.paused && !t.pausedUntil.IsZero() && !now.Before(t.pausedUntil) {
+		t.resumeLocked()
+	}
+	return t.paused
+}
@@ -0,0 +1,49 @@
+package goping
+
+import (
+	"context"
+	"fmt"
+)
+
+// Probe runs the check described by cfg once, as the scheduler would: with
+// its timeout, retries and deadline, after applying the same defaults and
+// validation as the config file. The result is returned but not recorded,
+// so no events, alerts, storage writes or run metrics come of it; metrics a
+// check exports itself, such as JSON values, are still set.
+//
+// A failed check returns its result along with a *CheckError saying why.
+// Any other error means cfg is invalid. Composite checks depend on other
+// targets and can't be probed on their own.
+func Probe(ctx context.Context, cfg CheckConfig) (CheckResult, error) {
+	if cfg.Type == "composite" {
+		return CheckResult{}, fmt.Errorf("check %q: composite checks can't be probed", cfg.Name)
+	}
+	if err := applyCheckDefaults(&cfg); err != nil {
+		return CheckResult{}, err
+	}
+	ch, err := newChecker(cfg)
+	if err != nil {
+		return CheckResult{}, err
+	}
+	t := &target{cfg: cfg, checker: ch}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Deadline)
+	defer cancel()
+
+	start := timeSource.Now()
+	err = t.attempt(ctx)
+	result := CheckResult{
+		Time:     start,
+		Check:    cfg.Name,
+		Type:     cfg.Type,
+		Tags:     cfg.Tags,
+		Up:       err == nil,
+		Duration: timeSource.Now().Sub(start).Seconds(),
+	}
+	if err != nil {
+		ce := classifyCheckError(err)
+		result.Error = ce.Error()
+		return result, ce
+	}
+	return result, nil
+}
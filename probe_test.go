@@ -0,0 +1,101 @@
+package goping
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	var requests atomic.Int32
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		status    int
+		requests  int32
+		category  string
+		retriable bool
+	}{
+		{name: "up", status: http.StatusOK, requests: 1},
+		{name: "server error", status: http.StatusBadGateway, requests: 2, category: categoryStatus, retriable: true},
+		{name: "not found", status: http.StatusNotFound, requests: 1, category: categoryStatus},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = tt.status
+			requests.Store(0)
+
+			cfg := CheckConfig{Name: "probe-test", Type: "http", URL: srv.URL, Tags: map[string]string{"env": "test"},
+				Retries: 1, RetryBackoff: time.Millisecond}
+			r, err := Probe(context.Background(), cfg)
+
+			if r.Check != "probe-test" || r.Type != "http" || r.Tags["env"] != "test" || r.Time.IsZero() {
+				t.Errorf("result %+v doesn't describe the check", r)
+			}
+			if n := requests.Load(); n != tt.requests {
+				t.Errorf("%d requests, want %d", n, tt.requests)
+			}
+			if tt.category == "" {
+				if err != nil || !r.Up {
+					t.Fatalf("Probe = %+v, %v, want up", r, err)
+				}
+				return
+			}
+			var ce *CheckError
+			if !errors.As(err, &ce) {
+				t.Fatalf("Probe error = %v, want a *CheckError", err)
+			}
+			if r.Up || r.Error != ce.Error() {
+				t.Errorf("result %+v, want down with error %q", r, ce.Error())
+			}
+			if ce.Category != tt.category || ce.Retriable != tt.retriable {
+				t.Errorf("error %+v, want category %s, retriable %t", ce, tt.category, tt.retriable)
+			}
+		})
+	}
+}
+
+func TestProbeInvalid(t *testing.T) {
+	for _, cfg := range []CheckConfig{
+		{Name: "no-url", Type: "http"},
+		{Name: "negative", Type: "http", URL: "http://example.com", Retries: -1},
+		{Name: "composite", Type: "composite", Expression: "a"},
+	} {
+		t.Run(cfg.Name, func(t *testing.T) {
+			_, err := Probe(context.Background(), cfg)
+			var ce *CheckError
+			if err == nil || errors.As(err, &ce) {
+				t.Errorf("Probe = %v, want a config error", err)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	if err := Ping(context.Background(), srv.URL); err != nil {
+		t.Fatalf("Ping = %v, want success", err)
+	}
+
+	// Client errors aren't retried, so this doesn't wait out a backoff.
+	status = http.StatusNotFound
+	err := Ping(context.Background(), srv.URL)
+	if err == nil || err.Category != categoryStatus || err.Retriable {
+		t.Errorf("Ping = %+v, want a status error that isn't retriable", err)
+	}
+}
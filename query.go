@@ -0,0 +1,189 @@
+package goping
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// page is the offset and limit of a paginated list request. A zero limit
+// returns everything after offset.
+type page struct {
+	offset int
+	limit  int
+}
+
+func parsePage(q url.Values) (page, error) {
+	var p page
+	for name, dst := range map[string]*int{"offset": &p.offset, "limit": &p.limit} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return page{}, fmt.Errorf("invalid %s %q: expected a non-negative integer", name, v)
+		}
+		*dst = n
+	}
+	return p, nil
+}
+
+// paginate returns the requested page of items and reports the total number
+// in the X-Total-Count header, so clients can page without a wrapper object.
+func paginate[T any](w http.ResponseWriter, items []T, p page) []T {
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(items)))
+	if p.offset >= len(items) {
+		return items[:0]
+	}
+	items = items[p.offset:]
+	if p.limit > 0 && p.limit < len(items) {
+		items = items[:p.limit]
+	}
+	return items
+}
+
+// parseTagFilters parses tag parameters of the form key=value. A target
+// must carry all of them to match.
+func parseTagFilters(values []string) (map[string]string, error) {
+	tags := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid tag filter %q: expected key=value", v)
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+func matchTags(tags, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := tags[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// state summarises a target for filtering and sorting: pending until its
+// first run, then paused, up or down.
+func (s targetStatus) state() string {
+	switch {
+	case s.Paused:
+		return "paused"
+	case s.LastRun.IsZero():
+		return "pending"
+	default:
+		return stateName(s.Up)
+	}
+}
+
+var (
+	targetStates = []string{"down", "pending", "paused", "up"}
+
+	// statusSorts compare target statuses for the sort parameter of
+	// /api/status. Ties are broken by name.
+	statusSorts = map[string]func(a, b targetStatus) int{
+		"name": func(a, b targetStatus) int { return strings.Compare(a.Name, b.Name) },
+		"status": func(a, b targetStatus) int {
+			return cmp.Compare(slices.Index(targetStates, a.state()), slices.Index(targetStates, b.state()))
+		},
+		"latency": func(a, b targetStatus) int { return cmp.Compare(a.LastDuration, b.LastDuration) },
+		"last_failure": func(a, b targetStatus) int {
+			return a.LastFailure.Compare(b.LastFailure)
+		},
+	}
+)
+
+// filterStatuses applies the filter, sort and page parameters of /api/status.
+func filterStatuses(w http.ResponseWriter, q url.Values, statuses []targetStatus) ([]targetStatus, error) {
+	tags, err := parseTagFilters(q["tag"])
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range q["state"] {
+		if !slices.Contains(targetStates, s) {
+			return nil, fmt.Errorf("invalid state %q: expected one of %s", s, strings.Join(targetStates, ", "))
+		}
+	}
+	p, err := parsePage(q)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses = slices.DeleteFunc(statuses, func(s targetStatus) bool {
+		if states := q["state"]; len(states) > 0 && !slices.Contains(states, s.state()) {
+			return true
+		}
+		if types := q["type"]; len(types) > 0 && !slices.Contains(types, s.Type) {
+			return true
+		}
+		return !matchTags(s.Tags, tags)
+	})
+
+	if sort := q.Get("sort"); sort != "" {
+		// A leading "-" sorts in descending order.
+		key, desc := strings.CutPrefix(sort, "-")
+		compare, ok := statusSorts[key]
+		if !ok {
+			return nil, fmt.Errorf("invalid sort %q: expected name, status, latency or last_failure", sort)
+		}
+		slices.SortStableFunc(statuses, func(a, b targetStatus) int {
+			c := compare(a, b)
+			if c == 0 {
+				c = strings.Compare(a.Name, b.Name)
+			}
+			if desc {
+				return -c
+			}
+			return c
+		})
+	}
+
+	return paginate(w, statuses, p), nil
+}
+
+// filterEvents applies the state, tag, sort and page parameters of
+// /api/events. The state is the one the target changed to, and tags are those
+// the target has now, so events of removed targets never match a tag filter.
+func filterEvents(w http.ResponseWriter, q url.Values, evs []Event, targets *targetSet) ([]Event, error) {
+	tags, err := parseTagFilters(q["tag"])
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range q["state"] {
+		if s != "up" && s != "down" {
+			return nil, fmt.Errorf("invalid state %q: expected up or down", s)
+		}
+	}
+	p, err := parsePage(q)
+	if err != nil {
+		return nil, err
+	}
+
+	evs = slices.DeleteFunc(evs, func(e Event) bool {
+		if states := q["state"]; len(states) > 0 && !slices.Contains(states, e.To) {
+			return true
+		}
+		if len(tags) == 0 {
+			return false
+		}
+		t, ok := targets.get(e.Check)
+		return !ok || !matchTags(t.cfg.Tags, tags)
+	})
+
+	switch q.Get("sort") {
+	case "", "time":
+	case "-time":
+		slices.Reverse(evs)
+	default:
+		return nil, fmt.Errorf("invalid sort %q: expected time or -time", q.Get("sort"))
+	}
+
+	return paginate(w, evs, p), nil
+}
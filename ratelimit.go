@@ -0,0 +1,119 @@
+package goping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+var rateLimitedRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goping_rate_limited_requests_total",
+		Help: "Requests that were delayed by the per-host rate limit",
+	},
+	[]string{"host"},
+)
+
+func init() {
+	prometheus.MustRegister(rateLimitedRequests)
+}
+
+// hostLimits is shared by every check, so the limit holds however many
+// targets point at the same host. Nil means no limits.
+var hostLimits *hostLimiter
+
+// RateLimitConfig caps the request rate goping sends to any single host.
+// Rate is in requests per second; Hosts overrides it for particular hosts.
+type RateLimitConfig struct {
+	Rate  float64            `yaml:"rate"`
+	Burst int                `yaml:"burst"`
+	Hosts map[string]float64 `yaml:"hosts"`
+}
+
+type hostLimiter struct {
+	cfg RateLimitConfig
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newHostLimiter(cfg RateLimitConfig) (*hostLimiter, error) {
+	if cfg.Rate < 0 {
+		return nil, fmt.Errorf("rate_limit: rate must not be negative")
+	}
+	for host, r := range cfg.Hosts {
+		if r < 0 {
+			return nil, fmt.Errorf("rate_limit: rate for %s must not be negative", host)
+		}
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	return &hostLimiter{cfg: cfg, limiters: make(map[string]*rate.Limiter)}, nil
+}
+
+// limiter returns the limiter for host, or nil if it is unlimited.
+func (l *hostLimiter) limiter(host string) *rate.Limiter {
+	if l == nil {
+		return nil
+	}
+
+	r, ok := l.cfg.Hosts[host]
+	if !ok {
+		r = l.cfg.Rate
+	}
+	if r == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lim, ok := l.limiters[host]
+	if !ok {
+		lim = rate.NewLimiter(rate.Limit(r), l.cfg.Burst)
+		l.limiters[host] = lim
+	}
+	return lim
+}
+
+// wait blocks until a request to host is allowed. It fails straight away if
+// the wait would outlast ctx, rather than running the check late.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	lim := l.limiter(host)
+	if lim == nil {
+		return nil
+	}
+
+	start := time.Now()
+	if err := lim.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limit for %s: %w", host, err)
+	}
+	if waited := time.Since(start); waited > time.Millisecond {
+		rateLimitedRequests.WithLabelValues(host).Inc()
+		traceFrom(ctx).phase("rate_limit", waited)
+	}
+	return nil
+}
+
+// redirectLimiter applies the host limits to the redirects an HTTP check
+// follows, which can lead to other hosts. The first request of a fetch waits
+// before it is sent, outside the latency measured, so only requests made for
+// a redirect wait here.
+type redirectLimiter struct {
+	next http.RoundTripper
+}
+
+func (t redirectLimiter) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Response != nil {
+		if err := hostLimits.wait(r.Context(), r.URL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(r)
+}
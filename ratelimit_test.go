@@ -0,0 +1,75 @@
+package goping
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterRates(t *testing.T) {
+	l, err := newHostLimiter(RateLimitConfig{Rate: 2, Hosts: map[string]float64{"fast.example.com": 0, "slow.example.com": 0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.limiter("fast.example.com") != nil {
+		t.Error("host with rate 0 is limited")
+	}
+	if lim := l.limiter("slow.example.com"); lim == nil || lim.Limit() != 0.5 {
+		t.Errorf("slow.example.com limiter = %v, want 0.5/s", lim)
+	}
+	if lim := l.limiter("other.example.com"); lim == nil || lim.Limit() != 2 {
+		t.Errorf("other.example.com limiter = %v, want the default 2/s", lim)
+	}
+	if l.limiter("other.example.com") != l.limiter("other.example.com") {
+		t.Error("a host's limiter isn't shared")
+	}
+
+	if _, err := newHostLimiter(RateLimitConfig{Rate: -1}); err == nil {
+		t.Error("negative rate accepted")
+	}
+}
+
+func TestRateLimitCoversRedirects(t *testing.T) {
+	// The target redirects to another host, told apart from it by name
+	// since both listen on the loopback address.
+	var moved atomic.Int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { moved.Add(1) }))
+	defer other.Close()
+	otherURL, _ := url.Parse(other.URL)
+	otherURL.Host = "localhost:" + otherURL.Port()
+	target := httptest.NewServer(http.RedirectHandler(otherURL.String()+"/moved", http.StatusFound))
+	defer target.Close()
+
+	old := hostLimits
+	t.Cleanup(func() { hostLimits = old })
+	var err error
+	hostLimits, err = newHostLimiter(RateLimitConfig{Hosts: map[string]float64{"localhost": 1.0 / 600}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := newHTTPChecker(CheckConfig{Name: "ratelimit-test", Type: "http", URL: target.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.check(ctx); err != nil {
+		t.Fatalf("first check: %v", err)
+	}
+
+	// The redirected host's one request per ten minutes is used up, so the
+	// next redirect to it can't be made in time.
+	err = c.check(ctx)
+	if err == nil || !strings.Contains(err.Error(), "rate limit for localhost") {
+		t.Errorf("second check = %v, want the redirect held by the rate limit", err)
+	}
+	if n := moved.Load(); n != 1 {
+		t.Errorf("redirected host got %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,146 @@
+package goping
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var redirectChainChanges = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goping_redirect_chain_changes_total",
+		Help: "Total number of times a redirect chain did not match the pinned or previously seen chain",
+	},
+	[]string{"check"},
+)
+
+func init() {
+	prometheus.MustRegister(redirectChainChanges)
+}
+
+// RedirectConfig asserts where an HTTP check's redirects lead. Host and
+// Path apply to the final URL; Chain pins every URL visited after the first.
+type RedirectConfig struct {
+	HTTPS bool     `yaml:"https,omitempty"`
+	Host  string   `yaml:"host,omitempty"`
+	Path  string   `yaml:"path,omitempty"`
+	Chain []string `yaml:"chain,omitempty"`
+}
+
+type redirectAsserter struct {
+	name string
+	cfg  RedirectConfig
+
+	// lastSeen holds the redirects last followed from each URL the check
+	// requests, the primary and each fallback.
+	mu       sync.Mutex
+	lastSeen map[string][]string
+}
+
+func newRedirectAsserter(name string, cfg RedirectConfig) *redirectAsserter {
+	return &redirectAsserter{name: name, cfg: cfg, lastSeen: make(map[string][]string)}
+}
+
+// redirectChain returns the URLs requested to get resp, starting with the
+// original request.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for r := resp.Request; r != nil; {
+		chain = append(chain, r.URL.String())
+		if r.Response == nil {
+			break
+		}
+		r = r.Response.Request
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// check verifies the chain of a response against the configuration. Without
+// a pinned chain, a change from the one previously seen from the same URL
+// fails that run, which alerts, and the new chain is expected from then on.
+func (a *redirectAsserter) check(ctx context.Context, chain []string) error {
+	tr := traceFrom(ctx)
+	final := chain[len(chain)-1]
+
+	if a.cfg.HTTPS {
+		err := checkHTTPS(chain)
+		tr.assert("redirect_https", err == nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.cfg.Host != "" || a.cfg.Path != "" {
+		u, err := url.Parse(final)
+		if err != nil {
+			return err
+		}
+		if a.cfg.Host != "" {
+			ok := strings.EqualFold(u.Host, a.cfg.Host)
+			tr.assert("redirect_host", ok)
+			if !ok {
+				return fmt.Errorf("redirected to host %s, expected %s", u.Host, a.cfg.Host)
+			}
+		}
+		if a.cfg.Path != "" {
+			ok := u.Path == a.cfg.Path
+			tr.assert("redirect_path", ok)
+			if !ok {
+				return fmt.Errorf("redirected to path %s, expected %s", u.Path, a.cfg.Path)
+			}
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	hops := chain[1:]
+	if a.cfg.Chain != nil {
+		ok := slices.Equal(hops, a.cfg.Chain)
+		tr.assert("redirect_chain", ok)
+		if !ok {
+			redirectChainChanges.WithLabelValues(a.name).Inc()
+			logger.Error("Redirect chain does not match pinned chain", "check", a.name, "expected", a.cfg.Chain, "got", hops)
+			return fmt.Errorf("redirect chain changed: %s", formatChain(chain))
+		}
+	}
+
+	previous, seen := a.lastSeen[chain[0]]
+	a.lastSeen[chain[0]] = hops
+	if a.cfg.Chain == nil && seen && !slices.Equal(hops, previous) {
+		redirectChainChanges.WithLabelValues(a.name).Inc()
+		logger.Error("Redirect chain changed", "check", a.name, "previous", previous, "got", hops)
+		tr.assert("redirect_chain", false)
+		return fmt.Errorf("redirect chain changed from %s to %s", formatChain(append(chain[:1:1], previous...)), formatChain(chain))
+	}
+	return nil
+}
+
+// checkHTTPS requires the chain to end on HTTPS and never to step down from
+// HTTPS to plain HTTP on the way.
+func checkHTTPS(chain []string) error {
+	secure := false
+	for _, u := range chain {
+		switch {
+		case strings.HasPrefix(u, "https://"):
+			secure = true
+		case secure:
+			return fmt.Errorf("redirect downgraded to plain HTTP: %s", formatChain(chain))
+		}
+	}
+	if !secure {
+		return fmt.Errorf("not redirected to HTTPS: %s", formatChain(chain))
+	}
+	return nil
+}
+
+func formatChain(chain []string) string {
+	return strings.Join(chain, " -> ")
+}
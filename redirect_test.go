@@ -0,0 +1,111 @@
+package goping
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCheckHTTPS(t *testing.T) {
+	tests := []struct {
+		chain   []string
+		wantErr string
+	}{
+		{[]string{"http://example.com/", "https://example.com/"}, ""},
+		{[]string{"https://example.com/"}, ""},
+		{[]string{"http://example.com/"}, "not redirected to HTTPS"},
+		{[]string{"http://example.com/", "https://example.com/", "http://www.example.com/"}, "downgraded to plain HTTP"},
+	}
+	for _, tt := range tests {
+		t.Run(formatChain(tt.chain), func(t *testing.T) {
+			err := checkHTTPS(tt.chain)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("checkHTTPS: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkHTTPS = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedirectAsserter(t *testing.T) {
+	const name = "redirect-test"
+	t.Cleanup(func() { redirectChainChanges.DeleteLabelValues(name) })
+
+	primary := []string{"http://example.com/", "https://www.example.com/"}
+	moved := []string{"http://example.com/", "https://example.net/"}
+	fallback := []string{"http://backup.example.com/", "https://backup.example.com/"}
+
+	tests := []struct {
+		name    string
+		cfg     RedirectConfig
+		chains  [][]string
+		wantErr []bool
+	}{
+		{
+			name:    "unpinned change",
+			chains:  [][]string{primary, primary, moved, moved},
+			wantErr: []bool{false, false, true, false},
+		},
+		{
+			// Each URL is compared with its own previous chain, so failing
+			// over and back isn't a change.
+			name:    "fallback and back",
+			chains:  [][]string{primary, fallback, primary, fallback},
+			wantErr: []bool{false, false, false, false},
+		},
+		{
+			name:    "pinned chain",
+			cfg:     RedirectConfig{Chain: primary[1:]},
+			chains:  [][]string{primary, moved, primary},
+			wantErr: []bool{false, true, false},
+		},
+		{
+			name:    "host",
+			cfg:     RedirectConfig{HTTPS: true, Host: "WWW.example.com"},
+			chains:  [][]string{primary, moved},
+			wantErr: []bool{false, true},
+		},
+		{
+			name:    "path",
+			cfg:     RedirectConfig{Path: "/"},
+			chains:  [][]string{primary, {"http://example.com/", "https://example.com/login"}},
+			wantErr: []bool{false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newRedirectAsserter(name, tt.cfg)
+			for i, chain := range tt.chains {
+				err := a.check(context.Background(), chain)
+				if got := err != nil; got != tt.wantErr[i] {
+					t.Errorf("run %d with %s: error %v, want an error %t", i+1, formatChain(chain), err, tt.wantErr[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRedirectWithFallbacks(t *testing.T) {
+	tests := []struct {
+		redirect RedirectConfig
+		ok       bool
+	}{
+		{RedirectConfig{HTTPS: true, Path: "/"}, true},
+		{RedirectConfig{Host: "www.example.com"}, false},
+		{RedirectConfig{Chain: []string{"https://www.example.com/"}}, false},
+	}
+	for _, tt := range tests {
+		cfg := CheckConfig{Name: "redirect-fallbacks", Type: "http", URL: "http://example.com/",
+			Fallbacks: []string{"http://backup.example.com/"}, Redirect: &tt.redirect}
+		_, err := newHTTPChecker(cfg)
+		if got := err == nil; got != tt.ok {
+			t.Errorf("newHTTPChecker with redirect %+v: %v, want success %t", tt.redirect, err, tt.ok)
+		}
+	}
+	fallbackActive.DeletePartialMatch(map[string]string{"check": "redirect-fallbacks"})
+}
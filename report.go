@@ -0,0 +1,96 @@
+package goping
+
+import (
+	"encoding/json"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// runStats counts what goping did over its lifetime for the shutdown report.
+// The Prometheus counters hold the same numbers split by label, but summing
+// them back up isn't worth it for one report.
+var runStats struct {
+	checksRun            atomic.Int64
+	checkFailures        atomic.Int64
+	notificationsSent    atomic.Int64
+	notificationsFailed  atomic.Int64
+	notificationsDropped atomic.Int64
+}
+
+// shutdownReport summarises a run of goping, mostly for short-lived runs in
+// CI where nobody scrapes the metrics.
+type shutdownReport struct {
+	Started time.Time `json:"started"`
+	Stopped time.Time `json:"stopped"`
+	Uptime  float64   `json:"uptimeSeconds"`
+
+	ChecksRun     int64    `json:"checksRun"`
+	CheckFailures int64    `json:"checkFailures"`
+	Down          []string `json:"down"`
+
+	NotificationsSent    int64 `json:"notificationsSent"`
+	NotificationsFailed  int64 `json:"notificationsFailed"`
+	NotificationsDropped int64 `json:"notificationsDropped"`
+
+	// Work that was cut short by the shutdown.
+	ChecksRunning       int `json:"checksRunning"`
+	NotificationsQueued int `json:"notificationsQueued"`
+}
+
+func newShutdownReport(started time.Time, targets *targetSet) shutdownReport {
+	now := timeSource.Now()
+	r := shutdownReport{
+		Started: started,
+		Stopped: now,
+		Uptime:  now.Sub(started).Seconds(),
+		Down:    []string{},
+
+		ChecksRun:     runStats.checksRun.Load(),
+		CheckFailures: runStats.checkFailures.Load(),
+
+		NotificationsSent:    runStats.notificationsSent.Load(),
+		NotificationsFailed:  runStats.notificationsFailed.Load(),
+		NotificationsDropped: runStats.notificationsDropped.Load(),
+	}
+
+	for _, t := range targets.list() {
+		t.mu.Lock()
+		if t.running {
+			r.ChecksRunning++
+		}
+		if t.seen && !t.up {
+			r.Down = append(r.Down, t.cfg.Name)
+		}
+		t.mu.Unlock()
+	}
+	if alerts != nil {
+		for _, ch := range alerts.channels {
+			r.NotificationsQueued += len(ch.queue)
+		}
+	}
+	return r
+}
+
+// log writes the report as a single structured log line.
+func (r shutdownReport) log() {
+	logger.Info("Shutdown report",
+		"uptime", time.Duration(r.Uptime*float64(time.Second)).Round(time.Second),
+		"checks_run", r.ChecksRun,
+		"check_failures", r.CheckFailures,
+		"down", r.Down,
+		"notifications_sent", r.NotificationsSent,
+		"notifications_failed", r.NotificationsFailed,
+		"notifications_dropped", r.NotificationsDropped,
+		"checks_running", r.ChecksRunning,
+		"notifications_queued", r.NotificationsQueued,
+	)
+}
+
+func (r shutdownReport) write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,139 @@
+package goping
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	dnsChanges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_dns_changes_total",
+			Help: "Times the set of addresses a check's host resolves to changed",
+		},
+		[]string{"check", "host"},
+	)
+
+	dnsAddresses = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "goping_dns_addresses",
+			Help: "Number of addresses a check's host resolved to at the last resolution",
+		},
+		[]string{"check", "host"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(dnsChanges)
+	prometheus.MustRegister(dnsAddresses)
+}
+
+// cachingResolver resolves a check's hosts itself and caches the addresses
+// for a fixed interval, whatever the records' TTL. Checks call resolve on
+// every run, so a change is noticed on schedule even while a kept-alive
+// connection means nothing is dialed; onChange then drops those connections
+// so the next request goes to the new addresses.
+type cachingResolver struct {
+	check    string
+	interval time.Duration
+	dialer   *net.Dialer
+	resolver *net.Resolver
+	onChange func()
+
+	mu    sync.Mutex
+	hosts map[string]resolvedHost
+}
+
+type resolvedHost struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+func newCachingResolver(check string, interval time.Duration, dialer *net.Dialer) *cachingResolver {
+	// Lookups go to the system's name servers but through the check's
+	// dialer, so they leave from its source address and interface too.
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dialerForNetwork(dialer, network).DialContext(ctx, network, address)
+		},
+	}
+	return &cachingResolver{check: check, interval: interval, dialer: dialer, resolver: resolver, hosts: make(map[string]resolvedHost)}
+}
+
+// resolve returns the addresses of host, looking them up again once the
+// cached ones are older than the interval.
+func (r *cachingResolver) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	now := timeSource.Now()
+	r.mu.Lock()
+	prev, ok := r.hosts[host]
+	r.mu.Unlock()
+	if ok && now.Before(prev.expires) {
+		return prev.addrs, nil
+	}
+
+	start := time.Now()
+	addrs, err := r.resolver.LookupNetIP(ctx, "ip", host)
+	traceFrom(ctx).phase("dns", time.Since(start))
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range addrs {
+		addrs[i] = a.Unmap()
+	}
+	slices.SortFunc(addrs, netip.Addr.Compare)
+	addrs = slices.Compact(addrs)
+
+	r.mu.Lock()
+	r.hosts[host] = resolvedHost{addrs: addrs, expires: now.Add(r.interval)}
+	r.mu.Unlock()
+
+	dnsAddresses.WithLabelValues(r.check, host).Set(float64(len(addrs)))
+	if ok && !slices.Equal(prev.addrs, addrs) {
+		dnsChanges.WithLabelValues(r.check, host).Inc()
+		logger.Info("Resolved addresses changed", "check", r.check, "host", host, "previous", prev.addrs, "addresses", addrs)
+		if r.onChange != nil {
+			r.onChange()
+		}
+	}
+	return addrs, nil
+}
+
+// DialContext dials the cached addresses of the host in address in turn
+// until one connects.
+func (r *cachingResolver) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return r.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := r.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, a := range addrs {
+		conn, err := r.dialer.DialContext(ctx, network, net.JoinHostPort(a.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,47 @@
+package goping
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCachingResolverUsesCheckDialer(t *testing.T) {
+	var mu sync.Mutex
+	var dialed []string
+	errBlocked := errors.New("blocked by test")
+	dialer := &net.Dialer{
+		LocalAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)},
+		ControlContext: func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			mu.Lock()
+			defer mu.Unlock()
+			dialed = append(dialed, network)
+			return errBlocked
+		},
+	}
+	r := newCachingResolver("resolver-test", time.Minute, dialer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if addrs, err := r.resolve(ctx, "goping.example.test"); err == nil {
+		t.Fatalf("resolve = %v, want the lookup to fail in the check's dialer", addrs)
+	}
+
+	// The source address, a *net.TCPAddr, was adapted for the UDP query, or
+	// the dial would have failed before reaching the control function.
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dialed) == 0 {
+		t.Fatal("lookup didn't use the check's dialer")
+	}
+	for _, network := range dialed {
+		if !strings.HasPrefix(network, "udp") && !strings.HasPrefix(network, "tcp") {
+			t.Errorf("dialed %s, want a DNS query", network)
+		}
+	}
+}
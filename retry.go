@@ -0,0 +1,56 @@
+package goping
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var checkRetries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goping_check_retries_total",
+		Help: "Failed check attempts that were retried",
+	},
+	[]string{"check"},
+)
+
+func init() {
+	prometheus.MustRegister(checkRetries)
+}
+
+// attempt runs the check, retrying retriable failures with exponential
+// backoff. Each attempt gets the check's timeout; ctx carries the deadline for
+// the whole run, and no retry is started that couldn't wait out its backoff in
+// time. Failures are returned as a *CheckError.
+func (t *target) attempt(ctx context.Context) error {
+	backoff := t.cfg.RetryBackoff
+	for n := 0; ; n++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, t.cfg.Timeout)
+		err := safeCheck(attemptCtx, t.cfg.Name, t.checker)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		ce := classifyCheckError(err)
+		if n >= t.cfg.Retries || !ce.Retriable {
+			return ce
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= backoff {
+			return classifyCheckError(fmt.Errorf("%w (deadline reached after %d attempts)", ce, n+1))
+		}
+
+		logger.Debug("Retrying check", "check", t.cfg.Name, "attempt", n+1, "backoff", backoff, "error", err)
+		checkRetries.WithLabelValues(t.cfg.Name).Inc()
+		traceFrom(ctx).retry()
+
+		select {
+		case <-ctx.Done():
+			return ce
+		case <-timeSource.After(backoff):
+		}
+		backoff *= 2
+	}
+}
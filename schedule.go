@@ -0,0 +1,143 @@
+package goping
+
+import (
+	"slices"
+	"time"
+)
+
+// scheduleEntry shows when a check runs and whether its runs keep up with
+// its interval. Every check is scheduled by its own goroutine, so a check
+// that is too slow for its interval shows up as running with a backlog or
+// missed runs rather than holding up others.
+type scheduleEntry struct {
+	Name     string  `json:"name"`
+	Interval float64 `json:"intervalSeconds"`
+	Overlap  string  `json:"overlap"`
+	Paused   bool    `json:"paused"`
+
+	NextRun      time.Time `json:"nextRun,omitzero"`
+	LastRun      time.Time `json:"lastRun,omitzero"`
+	LastDuration float64   `json:"lastDurationSeconds"`
+
+	// Load is the last run's duration as a fraction of the interval; at 1 or
+	// more the check can't keep up.
+	Load float64 `json:"load"`
+
+	Running      bool      `json:"running"`
+	RunningSince time.Time `json:"runningSince,omitzero"`
+	Backlog      int       `json:"backlog"`
+	Missed       int       `json:"missed"`
+}
+
+func (t *target) scheduleStatus() scheduleEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e := scheduleEntry{
+		Name:         t.cfg.Name,
+		Interval:     t.cfg.Interval.Seconds(),
+		Overlap:      t.cfg.Overlap,
+		Paused:       t.paused,
+		NextRun:      t.nextRun,
+		LastRun:      t.lastRun,
+		LastDuration: t.lastDuration,
+		Running:      t.running,
+		Missed:       t.missed,
+	}
+	if e.Interval > 0 {
+		e.Load = e.LastDuration / e.Interval
+	}
+	if t.running {
+		e.RunningSince = t.runStarted
+	}
+	if t.queued {
+		e.Backlog = 1
+	}
+	return e
+}
+
+// scheduleOf returns the schedule of every target, next to run first.
+// Targets that haven't been scheduled yet come last.
+func scheduleOf(targets *targetSet) []scheduleEntry {
+	out := []scheduleEntry{}
+	for _, t := range targets.list() {
+		out = append(out, t.scheduleStatus())
+	}
+	slices.SortStableFunc(out, func(a, b scheduleEntry) int {
+		switch {
+		case a.NextRun.IsZero() && b.NextRun.IsZero():
+			return 0
+		case a.NextRun.IsZero():
+			return 1
+		case b.NextRun.IsZero():
+			return -1
+		}
+		return a.NextRun.Compare(b.NextRun)
+	})
+	return out
+}
+
+const (
+	// The dashboard's schedule view shows scheduleBefore of the past and the
+	// rest of scheduleWindow into the future.
+	scheduleWindow = 5 * time.Minute
+	scheduleBefore = time.Minute
+
+	// scheduleMaxBars caps the bars per row for checks with short intervals.
+	scheduleMaxBars = 300
+)
+
+// ganttRow is one check's line in the dashboard's schedule view. Bars are
+// positioned in percent of the window.
+type ganttRow struct {
+	scheduleEntry
+	Bars []ganttBar
+}
+
+type ganttBar struct {
+	Left, Width float64
+	Kind        string
+}
+
+// ganttRows lays out the last, current and upcoming runs of each check in
+// the window around now. Upcoming runs are drawn as long as the last one
+// took. A check whose runs take as long as its interval is marked as
+// overrunning.
+func ganttRows(entries []scheduleEntry, now time.Time) []ganttRow {
+	start := now.Add(-scheduleBefore)
+	pct := func(t time.Time) float64 {
+		return float64(t.Sub(start)) / float64(scheduleWindow) * 100
+	}
+	bar := func(from time.Time, d time.Duration, kind string) ganttBar {
+		left := max(pct(from), 0)
+		right := min(pct(from.Add(d)), 100)
+		return ganttBar{Left: left, Width: max(right-left, 0.2), Kind: kind}
+	}
+
+	rows := make([]ganttRow, 0, len(entries))
+	for _, e := range entries {
+		row := ganttRow{scheduleEntry: e}
+		last := time.Duration(e.LastDuration * float64(time.Second))
+		interval := time.Duration(e.Interval * float64(time.Second))
+
+		past, upcoming := "past", "next"
+		if e.Load >= 1 {
+			past, upcoming = "overrun", "overrun"
+		}
+		if !e.LastRun.IsZero() && !e.Running && e.LastRun.Add(last).After(start) {
+			row.Bars = append(row.Bars, bar(e.LastRun, last, past))
+		}
+		if e.Running {
+			row.Bars = append(row.Bars, bar(e.RunningSince, now.Sub(e.RunningSince), "running"))
+		}
+
+		end := start.Add(scheduleWindow)
+		if !e.Paused && !e.NextRun.IsZero() && interval > 0 {
+			for next := e.NextRun; next.Before(end) && len(row.Bars) < scheduleMaxBars; next = next.Add(interval) {
+				row.Bars = append(row.Bars, bar(next, last, upcoming))
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -0,0 +1,360 @@
+package goping
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// silenceRetention is how long expired silences remain visible, matching
+// Alertmanager's default.
+const silenceRetention = 120 * time.Hour
+
+var silences = newSilenceStore()
+
+// matcher and silence mirror Alertmanager's v2 API so existing tooling and
+// habits carry over.
+type matcher struct {
+	Name    string `json:"name" yaml:"name"`
+	Value   string `json:"value" yaml:"value"`
+	IsRegex bool   `json:"isRegex" yaml:"is_regex"`
+	IsEqual *bool  `json:"isEqual,omitempty" yaml:"is_equal,omitempty"`
+
+	re *regexp.Regexp
+}
+
+type silenceStatus struct {
+	State string `json:"state"`
+}
+
+type silence struct {
+	ID        string        `json:"id" yaml:"id"`
+	Matchers  []matcher     `json:"matchers" yaml:"matchers"`
+	StartsAt  time.Time     `json:"startsAt" yaml:"starts_at"`
+	EndsAt    time.Time     `json:"endsAt" yaml:"ends_at"`
+	UpdatedAt time.Time     `json:"updatedAt" yaml:"updated_at"`
+	CreatedBy string        `json:"createdBy" yaml:"created_by"`
+	Comment   string        `json:"comment" yaml:"comment,omitempty"`
+	Status    silenceStatus `json:"status" yaml:"-"`
+}
+
+func (m *matcher) equal() bool {
+	return m.IsEqual == nil || *m.IsEqual
+}
+
+func (m *matcher) compile() error {
+	if m.Name == "" {
+		return errors.New("matcher name is required")
+	}
+	if m.IsRegex {
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return fmt.Errorf("matcher %q: %w", m.Name, err)
+		}
+		m.re = re
+	}
+	return nil
+}
+
+func (m *matcher) matches(labels map[string]string) bool {
+	v := labels[m.Name]
+	var ok bool
+	if m.IsRegex {
+		ok = m.re.MatchString(v)
+	} else {
+		ok = v == m.Value
+	}
+	return ok == m.equal()
+}
+
+func (m matcher) String() string {
+	var op string
+	switch {
+	case m.equal() && !m.IsRegex:
+		op = "="
+	case !m.equal() && !m.IsRegex:
+		op = "!="
+	case m.equal() && m.IsRegex:
+		op = "=~"
+	default:
+		op = "!~"
+	}
+	return fmt.Sprintf("%s%s%q", m.Name, op, m.Value)
+}
+
+// parseMatchers parses amtool-style matchers such as `check=web, env=~prod.*`.
+func parseMatchers(s string) ([]matcher, error) {
+	var matchers []matcher
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' }) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		i := strings.IndexAny(part, "=!")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid matcher %q", part)
+		}
+
+		m := matcher{Name: strings.TrimSpace(part[:i])}
+		rest := part[i:]
+		for _, op := range []string{"!~", "=~", "!=", "="} {
+			if strings.HasPrefix(rest, op) {
+				m.IsRegex = strings.HasSuffix(op, "~")
+				equal := !strings.HasPrefix(op, "!")
+				m.IsEqual = &equal
+				m.Value = strings.Trim(strings.TrimSpace(rest[len(op):]), `"`)
+				break
+			}
+		}
+		if m.IsEqual == nil {
+			return nil, fmt.Errorf("invalid matcher %q", part)
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+type silenceStore struct {
+	mu       sync.Mutex
+	silences map[string]*silence
+}
+
+func newSilenceStore() *silenceStore {
+	return &silenceStore{silences: make(map[string]*silence)}
+}
+
+func (s *silence) state(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return "pending"
+	case now.Before(s.EndsAt):
+		return "active"
+	default:
+		return "expired"
+	}
+}
+
+func (s *silence) matches(labels map[string]string) bool {
+	for i := range s.Matchers {
+		if !s.Matchers[i].matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func newSilenceID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// set creates or updates a silence. As in Alertmanager, updating a silence
+// that is already active in a way that changes what it matches expires it and
+// creates a new one instead.
+func (st *silenceStore) set(s silence) (string, error) {
+	now := timeSource.Now()
+	if err := s.validate(now); err != nil {
+		return "", err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.gc(now)
+
+	if s.ID != "" {
+		prev, ok := st.silences[s.ID]
+		if !ok {
+			return "", fmt.Errorf("silence %s not found", s.ID)
+		}
+		if prev.state(now) == "active" && !sameMatchers(prev.Matchers, s.Matchers) {
+			prev.EndsAt = now
+			prev.UpdatedAt = now
+			s.ID = ""
+		} else if prev.state(now) == "expired" {
+			s.ID = ""
+		}
+	}
+	if s.ID == "" {
+		s.ID = newSilenceID()
+	}
+
+	s.UpdatedAt = now
+	st.silences[s.ID] = &s
+	return s.ID, nil
+}
+
+// validate compiles the matchers and checks the time range, defaulting
+// StartsAt to now.
+func (s *silence) validate(now time.Time) error {
+	if len(s.Matchers) == 0 {
+		return errors.New("at least one matcher is required")
+	}
+	for i := range s.Matchers {
+		if err := s.Matchers[i].compile(); err != nil {
+			return err
+		}
+	}
+
+	if s.StartsAt.IsZero() {
+		s.StartsAt = now
+	}
+	if s.EndsAt.IsZero() {
+		return errors.New("endsAt is required")
+	}
+	if !s.EndsAt.After(s.StartsAt) {
+		return errors.New("endsAt must be after startsAt")
+	}
+	if s.EndsAt.Before(now) {
+		return errors.New("endsAt is in the past")
+	}
+	return nil
+}
+
+// replace makes the silences created by source exactly ss. Silences must carry
+// stable IDs so unchanged entries are left untouched between syncs.
+func (st *silenceStore) replace(source string, ss []silence) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := timeSource.Now()
+	keep := make(map[string]bool, len(ss))
+	for _, s := range ss {
+		keep[s.ID] = true
+		if _, ok := st.silences[s.ID]; ok {
+			continue
+		}
+		s.CreatedBy = source
+		s.UpdatedAt = now
+		st.silences[s.ID] = &s
+	}
+
+	for id, s := range st.silences {
+		if s.CreatedBy == source && !keep[id] {
+			delete(st.silences, id)
+		}
+	}
+}
+
+// restore adds silences from a snapshot under their original IDs, skipping
+// any that have expired in the meantime.
+func (st *silenceStore) restore(ss []silence) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := timeSource.Now()
+	for _, s := range ss {
+		if s.state(now) == "expired" {
+			continue
+		}
+		for i := range s.Matchers {
+			if err := s.Matchers[i].compile(); err != nil {
+				return fmt.Errorf("silence %s: %w", s.ID, err)
+			}
+		}
+		st.silences[s.ID] = &s
+	}
+	return nil
+}
+
+func sameMatchers(a, b []matcher) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].String() != b[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+// expire ends a silence immediately. Expired silences cannot be expired again.
+func (st *silenceStore) expire(id string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := timeSource.Now()
+	s, ok := st.silences[id]
+	if !ok {
+		return fmt.Errorf("silence %s not found", id)
+	}
+	if s.state(now) == "expired" {
+		return fmt.Errorf("silence %s already expired", id)
+	}
+
+	if s.StartsAt.After(now) {
+		s.StartsAt = now
+	}
+	s.EndsAt = now
+	s.UpdatedAt = now
+	return nil
+}
+
+func (st *silenceStore) get(id string) (silence, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s, ok := st.silences[id]
+	if !ok {
+		return silence{}, false
+	}
+	out := *s
+	out.Status.State = s.state(timeSource.Now())
+	return out, true
+}
+
+// list returns all silences, active first, then pending, then expired.
+func (st *silenceStore) list() []silence {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := timeSource.Now()
+	st.gc(now)
+
+	out := make([]silence, 0, len(st.silences))
+	for _, s := range st.silences {
+		c := *s
+		c.Status.State = s.state(now)
+		out = append(out, c)
+	}
+
+	order := map[string]int{"active": 0, "pending": 1, "expired": 2}
+	sort.Slice(out, func(i, j int) bool {
+		if order[out[i].Status.State] != order[out[j].Status.State] {
+			return order[out[i].Status.State] < order[out[j].Status.State]
+		}
+		return out[i].EndsAt.Before(out[j].EndsAt)
+	})
+	return out
+}
+
+// matching returns an active silence matching labels, or nil.
+func (st *silenceStore) matching(labels map[string]string, now time.Time) *silence {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	for _, s := range st.silences {
+		if s.state(now) == "active" && s.matches(labels) {
+			c := *s
+			return &c
+		}
+	}
+	return nil
+}
+
+func (st *silenceStore) gc(now time.Time) {
+	for id, s := range st.silences {
+		if now.Sub(s.EndsAt) > silenceRetention {
+			delete(st.silences, id)
+		}
+	}
+}
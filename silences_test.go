@@ -0,0 +1,161 @@
+package goping
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMatchers(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "check=web", want: []string{`check="web"`}},
+		{in: `check="web"`, want: []string{`check="web"`}},
+		{in: " check = web , env=~prod.* ", want: []string{`check="web"`, `env=~"prod.*"`}},
+		{in: "check!=web\nenv!~staging|dev", want: []string{`check!="web"`, `env!~"staging|dev"`}},
+		{in: "check=", want: []string{`check=""`}},
+		{in: "check=web,,", want: []string{`check="web"`}},
+		{in: "", want: nil},
+		{in: "=web", wantErr: true},
+		{in: "web", wantErr: true},
+		{in: "check!web", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			ms, err := parseMatchers(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseMatchers(%q) = %v, want an error", tt.in, ms)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMatchers(%q): %v", tt.in, err)
+			}
+			var got []string
+			for _, m := range ms {
+				got = append(got, m.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseMatchers(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSilenceMatches(t *testing.T) {
+	labels := map[string]string{"check": "api-eu", "type": "http", "env": "prod"}
+	tests := []struct {
+		matchers string
+		want     bool
+	}{
+		{"check=api-eu", true},
+		{"check=api", false},
+		{"check!=api", true},
+		{"check=~api-.*", true},
+		// Regexes are anchored.
+		{"check=~api", false},
+		{"check!~api-(us|eu)", false},
+		{"check=api-eu, env=prod", true},
+		{"check=api-eu, env=staging", false},
+		// A missing label has the empty value.
+		{"team=", true},
+		{"team!=", false},
+		{"team=~.*", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.matchers, func(t *testing.T) {
+			ms, err := parseMatchers(tt.matchers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := silence{Matchers: ms}
+			for i := range s.Matchers {
+				if err := s.Matchers[i].compile(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := s.matches(labels); got != tt.want {
+				t.Errorf("%s matches %v = %t, want %t", tt.matchers, labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSilenceStoreMatching(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	old := timeSource
+	t.Cleanup(func() { timeSource = old })
+	timeSource = newFakeClock(now)
+
+	st := newSilenceStore()
+	ms, err := parseMatchers("check=web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := st.set(silence{Matchers: ms, StartsAt: now.Add(time.Hour), EndsAt: now.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	web := map[string]string{"check": "web"}
+	tests := []struct {
+		name   string
+		labels map[string]string
+		at     time.Time
+		want   bool
+	}{
+		{"pending", web, now, false},
+		{"starting", web, now.Add(time.Hour), true},
+		{"active", web, now.Add(90 * time.Minute), true},
+		{"other check", map[string]string{"check": "api"}, now.Add(90 * time.Minute), false},
+		{"ended", web, now.Add(2 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := st.matching(tt.labels, tt.at)
+			if got := s != nil; got != tt.want {
+				t.Fatalf("matching at %s = %v, want a match %t", tt.at, s, tt.want)
+			}
+			if s != nil && s.ID != id {
+				t.Errorf("matched silence %s, want %s", s.ID, id)
+			}
+		})
+	}
+}
+
+func TestSilenceValidate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	web := []matcher{{Name: "check", Value: "web"}}
+	tests := []struct {
+		name    string
+		s       silence
+		wantErr string
+	}{
+		{"valid", silence{Matchers: web, EndsAt: now.Add(time.Hour)}, ""},
+		{"no matchers", silence{EndsAt: now.Add(time.Hour)}, "at least one matcher is required"},
+		{"no name", silence{Matchers: []matcher{{Value: "web"}}, EndsAt: now.Add(time.Hour)}, "matcher name is required"},
+		{"bad regex", silence{Matchers: []matcher{{Name: "check", Value: "(", IsRegex: true}}, EndsAt: now.Add(time.Hour)}, "missing closing )"},
+		{"no end", silence{Matchers: web}, "endsAt is required"},
+		{"ends before start", silence{Matchers: web, StartsAt: now.Add(2 * time.Hour), EndsAt: now.Add(time.Hour)}, "endsAt must be after startsAt"},
+		{"ended", silence{Matchers: web, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)}, "endsAt is in the past"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.s.validate(now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
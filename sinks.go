@@ -0,0 +1,68 @@
+package goping
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sinkWritten = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_sink_results_written_total",
+			Help: "Total number of check results written to an output sink",
+		},
+		[]string{"sink"},
+	)
+
+	sinkDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_sink_results_dropped_total",
+			Help: "Total number of check results an output sink failed to write",
+		},
+		[]string{"sink"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(sinkWritten)
+	prometheus.MustRegister(sinkDropped)
+}
+
+var sinks []resultSink
+
+type SinksConfig struct {
+	Elasticsearch *ElasticsearchConfig `yaml:"elasticsearch"`
+}
+
+// CheckResult is the outcome of a single check run, as published to sinks
+// and kept in Storage.
+type CheckResult struct {
+	Time     time.Time         `json:"timestamp"`
+	Check    string            `json:"check"`
+	Type     string            `json:"type"`
+	Tags     map[string]string `json:"tags,omitempty"`
+	Up       bool              `json:"up"`
+	Duration float64           `json:"duration_seconds"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// resultSink receives every check result. write is called from the check's
+// goroutine and must not block; run delivers results until ctx is done.
+type resultSink interface {
+	write(r CheckResult)
+	run(ctx context.Context)
+}
+
+func newSinks(cfg SinksConfig) ([]resultSink, error) {
+	var out []resultSink
+	if cfg.Elasticsearch != nil {
+		es, err := newElasticsearchSink(*cfg.Elasticsearch)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, es)
+	}
+	return out, nil
+}
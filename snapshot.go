@@ -0,0 +1,173 @@
+package goping
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// snapshot is the runtime state that isn't in the config file: targets added
+// through the API, silences and the last known state of every check. It is
+// YAML so runtime targets read the same as in the config.
+type snapshot struct {
+	Time     time.Time     `yaml:"time"`
+	Targets  []CheckConfig `yaml:"targets,omitempty"`
+	Silences []silence     `yaml:"silences,omitempty"`
+	State    []targetState `yaml:"state,omitempty"`
+}
+
+// targetState is a target's incident state, restored so that a check which
+// was already down doesn't alert again after a restart or migration.
+type targetState struct {
+	Name        string    `yaml:"name"`
+	Up          bool      `yaml:"up"`
+	Since       time.Time `yaml:"since,omitempty"`
+	LastRun     time.Time `yaml:"last_run,omitempty"`
+	LastError   string    `yaml:"last_error,omitempty"`
+	Paused      bool      `yaml:"paused,omitempty"`
+	PausedUntil time.Time `yaml:"paused_until,omitempty"`
+}
+
+func takeSnapshot(targets *targetSet) snapshot {
+	snap := snapshot{Time: timeSource.Now()}
+
+	for _, t := range targets.list() {
+		if t.runtime {
+			snap.Targets = append(snap.Targets, t.cfg)
+		}
+
+		t.mu.Lock()
+		if t.seen || t.paused {
+			st := targetState{
+				Name:        t.cfg.Name,
+				Up:          t.up,
+				Since:       t.since,
+				LastRun:     t.lastRun,
+				Paused:      t.paused,
+				PausedUntil: t.pausedUntil,
+			}
+			if t.lastErr != nil {
+				st.LastError = t.lastErr.Error()
+			}
+			snap.State = append(snap.State, st)
+		}
+		t.mu.Unlock()
+	}
+
+	// Maintenance silences are synced from their calendars again on startup.
+	for _, s := range silences.list() {
+		if s.Status.State != "expired" && !strings.HasPrefix(s.CreatedBy, "maintenance:") {
+			snap.Silences = append(snap.Silences, s)
+		}
+	}
+
+	return snap
+}
+
+// restoreSnapshot loads a snapshot written by `goping snapshot` or
+// GET /api/snapshot. It must run before checks are started.
+func restoreSnapshot(path string, targets *targetSet) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := yaml.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+
+	for _, cfg := range snap.Targets {
+		if _, ok := targets.get(cfg.Name); ok {
+			logger.Warn("Snapshot target is already defined, keeping the existing one", "check", cfg.Name)
+			continue
+		}
+		if _, err := targets.add(cfg); err != nil {
+			return err
+		}
+	}
+
+	if err := silences.restore(snap.Silences); err != nil {
+		return err
+	}
+
+	for _, st := range snap.State {
+		t, ok := targets.get(st.Name)
+		if !ok {
+			logger.Warn("Snapshot has state for an unknown check", "check", st.Name)
+			continue
+		}
+
+		t.mu.Lock()
+		t.seen = !st.LastRun.IsZero()
+		t.up = st.Up
+		t.since = st.Since
+		t.lastRun = st.LastRun
+		if st.LastError != "" {
+			t.lastErr = errors.New(st.LastError)
+		}
+		t.paused = st.Paused
+		t.pausedUntil = st.PausedUntil
+		t.mu.Unlock()
+	}
+
+	logger.Info("Restored snapshot", "path", path, "taken", snap.Time, "targets", len(snap.Targets), "silences", len(snap.Silences), "states", len(snap.State))
+	return nil
+}
+
+// runSnapshot implements `goping snapshot`, which saves the runtime state of
+// a running instance to a file for `goping -restore`.
+func runSnapshot(args []string) int {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	url := fs.String("url", "http://localhost:8080", "base URL of the running goping")
+	token := fs.String("token", "", "API token (default $API_TOKEN)")
+	outPath := fs.String("o", "", "write the snapshot here instead of stdout")
+	fs.Parse(args)
+
+	if *token == "" {
+		*token = getEnv("API_TOKEN")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(*url, "/")+"/api/snapshot", nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	req.Header.Set("Authorization", "Bearer "+*token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "snapshot failed: %s: %s\n", resp.Status, strings.TrimSpace(string(data)))
+		return 1
+	}
+
+	if *outPath == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*outPath, data, 0o600); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", *outPath)
+	return 0
+}
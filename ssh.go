@@ -0,0 +1,151 @@
+package goping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/ssh"
+)
+
+var sshHostKeyChanges = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "goping_ssh_host_key_changes_total",
+		Help: "Total number of times an SSH host key did not match the pinned or previously seen key",
+	},
+	[]string{"check"},
+)
+
+func init() {
+	prometheus.MustRegister(sshHostKeyChanges)
+}
+
+var errHostKeyMismatch = errors.New("host key mismatch")
+
+type sshChecker struct {
+	name    string
+	address string
+	pinned  string
+	dialer  *net.Dialer
+
+	mu       sync.Mutex
+	lastSeen string
+}
+
+func newSSHChecker(cfg CheckConfig) (*sshChecker, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("check %q: address is required", cfg.Name)
+	}
+
+	address := cfg.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+
+	dialer, err := newDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sshChecker{
+		name:    cfg.Name,
+		address: address,
+		pinned:  cfg.HostKeyFingerprint,
+		dialer:  dialer,
+	}, nil
+}
+
+// check completes the SSH key exchange and verifies the host key. Authentication
+// is not attempted, so a rejected login after a verified key counts as success.
+func (c *sshChecker) check(ctx context.Context) error {
+	tr := traceFrom(ctx)
+
+	host, _, _ := net.SplitHostPort(c.address)
+	if err := hostLimits.wait(ctx, host); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	conn, err := c.dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	tr.phase("connect", time.Since(start))
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	verified := false
+	config := &ssh.ClientConfig{
+		User: "goping",
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			err := c.verifyHostKey(ssh.FingerprintSHA256(key))
+			tr.assert("host_key", err == nil)
+			if err != nil {
+				return err
+			}
+			verified = true
+			return nil
+		},
+	}
+
+	handshakeStart := time.Now()
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, c.address, config)
+	tr.phase("handshake", time.Since(handshakeStart))
+	if err != nil {
+		if verified {
+			return nil
+		}
+		if errors.Is(err, errHostKeyMismatch) {
+			return assertionError(err, false)
+		}
+		return err
+	}
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		for ch := range chans {
+			ch.Reject(ssh.Prohibited, "goping does not accept channels")
+		}
+	}()
+
+	return sshConn.Close()
+}
+
+func (c *sshChecker) verifyHostKey(fingerprint string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pinned != "" && fingerprint != c.pinned {
+		sshHostKeyChanges.WithLabelValues(c.name).Inc()
+		logger.Error("SSH host key does not match pinned fingerprint", "check", c.name, "address", c.address, "expected", c.pinned, "got", fingerprint)
+		return fmt.Errorf("%w: got %s", errHostKeyMismatch, fingerprint)
+	}
+
+	// Without a pin the first key seen is trusted, as with known_hosts, and a
+	// different one fails the check until it is pinned or forgotten.
+	if c.pinned == "" && c.lastSeen != "" && fingerprint != c.lastSeen {
+		sshHostKeyChanges.WithLabelValues(c.name).Inc()
+		logger.Error("SSH host key changed", "check", c.name, "address", c.address, "previous", c.lastSeen, "got", fingerprint)
+		return fmt.Errorf("%w: key changed from %s to %s", errHostKeyMismatch, c.lastSeen, fingerprint)
+	}
+
+	c.lastSeen = fingerprint
+	return nil
+}
+
+// forgetHostKey drops the key learned without a pin, so the next key seen is
+// trusted again. It reports whether there was one.
+func (c *sshChecker) forgetHostKey() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	had := c.lastSeen != ""
+	c.lastSeen = ""
+	return had
+}
@@ -0,0 +1,77 @@
+package goping
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSHHostKeyTrustOnFirstUse(t *testing.T) {
+	c := &sshChecker{name: "bastion", address: "bastion:22"}
+	if err := c.verifyHostKey("SHA256:old"); err != nil {
+		t.Fatalf("first key: %v", err)
+	}
+
+	// A changed key keeps failing, not just the first time it is seen.
+	for range 2 {
+		if err := c.verifyHostKey("SHA256:new"); !errors.Is(err, errHostKeyMismatch) {
+			t.Fatalf("changed key = %v, want a mismatch", err)
+		}
+	}
+
+	if !c.forgetHostKey() {
+		t.Error("forgetHostKey reported no learned key")
+	}
+	if err := c.verifyHostKey("SHA256:new"); err != nil {
+		t.Errorf("new key after forgetting: %v", err)
+	}
+}
+
+func TestSSHHostKeyPinned(t *testing.T) {
+	c := &sshChecker{name: "bastion", address: "bastion:22", pinned: "SHA256:pinned"}
+	if err := c.verifyHostKey("SHA256:pinned"); err != nil {
+		t.Fatalf("pinned key: %v", err)
+	}
+	if err := c.verifyHostKey("SHA256:other"); !errors.Is(err, errHostKeyMismatch) {
+		t.Errorf("other key = %v, want a mismatch", err)
+	}
+}
+
+func TestForgetHostKeyAPI(t *testing.T) {
+	learned := &sshChecker{name: "bastion", address: "bastion:22"}
+	learned.verifyHostKey("SHA256:old")
+	targets := newTargetSet([]*target{
+		{cfg: CheckConfig{Name: "bastion", Type: "ssh"}, checker: learned},
+		{cfg: CheckConfig{Name: "pinned", Type: "ssh"}, checker: &sshChecker{name: "pinned", pinned: "SHA256:pinned"}},
+		{cfg: CheckConfig{Name: "web", Type: "http"}, checker: &httpChecker{}},
+	})
+	mux := http.NewServeMux()
+	registerAPI(mux, APIConfig{Token: "secret"}, targets)
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"bastion", "", http.StatusUnauthorized},
+		{"bastion", "secret", http.StatusNoContent},
+		{"pinned", "secret", http.StatusBadRequest},
+		{"web", "secret", http.StatusBadRequest},
+		{"missing", "secret", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, "/api/targets/"+tt.name+"/host-key", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("DELETE %s host key with token %q = %d, want %d", tt.name, tt.token, rec.Code, tt.want)
+		}
+	}
+	if err := learned.verifyHostKey("SHA256:new"); err != nil {
+		t.Errorf("new key after the API forgot the old one: %v", err)
+	}
+}
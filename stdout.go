@@ -0,0 +1,55 @@
+package goping
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+const stdoutQueueSize = 1000
+
+// stdoutSink writes each check result as one line of JSON, for piping into
+// jq, vector and the like. Logs go to stderr while it is enabled.
+type stdoutSink struct {
+	enc   *json.Encoder
+	queue chan CheckResult
+}
+
+func newStdoutSink(w io.Writer) *stdoutSink {
+	return &stdoutSink{enc: json.NewEncoder(w), queue: make(chan CheckResult, stdoutQueueSize)}
+}
+
+func (s *stdoutSink) write(r CheckResult) {
+	select {
+	case s.queue <- r:
+	default:
+		sinkDropped.WithLabelValues("stdout").Inc()
+	}
+}
+
+// run writes queued results until ctx is done, then drains the queue.
+func (s *stdoutSink) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case r := <-s.queue:
+					s.encode(r)
+				default:
+					return
+				}
+			}
+		case r := <-s.queue:
+			s.encode(r)
+		}
+	}
+}
+
+func (s *stdoutSink) encode(r CheckResult) {
+	if err := s.enc.Encode(r); err != nil {
+		sinkDropped.WithLabelValues("stdout").Inc()
+		return
+	}
+	sinkWritten.WithLabelValues("stdout").Inc()
+}
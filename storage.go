@@ -0,0 +1,168 @@
+package goping
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultResultsSize = 1000
+
+// Storage keeps check results and the incidents derived from them, the state
+// transitions recorded as events. The default keeps them in memory; SQLite
+// and Postgres are available for keeping them durably, and programs that
+// embed goping can add any other backend with RegisterStorage.
+type Storage interface {
+	AddResult(r CheckResult) error
+	AddEvent(e Event) error
+
+	// Results and Events return entries oldest first, filtered to the given
+	// checks (all if empty) and the half-open range [since, until). Zero
+	// times are unbounded.
+	Results(checks []string, since, until time.Time) ([]CheckResult, error)
+	Events(checks []string, since, until time.Time) ([]Event, error)
+
+	Close() error
+}
+
+var store Storage = newMemoryStorage(newEventLog(defaultEventsSize), defaultResultsSize)
+
+// StorageOpener opens a storage backend registered with RegisterStorage. It
+// is given the config's storage section, DSN and all.
+type StorageOpener func(cfg StorageConfig) (Storage, error)
+
+var storageDrivers = map[string]StorageOpener{}
+
+// RegisterStorage makes a backend available to configs as storage driver
+// name. Like the SQL backends it is written to in the background through a
+// buffer, so its AddResult and AddEvent can be slow, or fail and be retried,
+// without holding up checks. RegisterStorage is called before Main,
+// typically from init, and panics if name is already taken.
+func RegisterStorage(name string, open StorageOpener) {
+	switch name {
+	case "", "memory", "sqlite", "postgres":
+		panic(fmt.Sprintf("goping: storage driver %q is built in", name))
+	}
+	if _, ok := storageDrivers[name]; ok {
+		panic(fmt.Sprintf("goping: storage driver %q registered twice", name))
+	}
+	storageDrivers[name] = open
+}
+
+// StorageConfig selects the storage backend. Driver is memory (the default),
+// sqlite or postgres; DSN is the database file or connection string.
+type StorageConfig struct {
+	Driver string `yaml:"driver"`
+	DSN    string `yaml:"dsn"`
+
+	// Results is how many results the memory backend keeps.
+	Results int `yaml:"results"`
+	// Retention is how long the SQL backends keep results and events.
+	Retention time.Duration `yaml:"retention"`
+	// Buffer is how many writes the SQL backends queue in memory while the
+	// database is unavailable.
+	Buffer int `yaml:"buffer"`
+}
+
+func openStorage(cfg StorageConfig, events EventsConfig) (Storage, error) {
+	switch cfg.Driver {
+	case "", "memory":
+		l, err := openEventLog(events)
+		if err != nil {
+			return nil, err
+		}
+		size := cfg.Results
+		if size <= 0 {
+			size = defaultResultsSize
+		}
+		return newMemoryStorage(l, size), nil
+	case "sqlite", "postgres":
+		if cfg.DSN == "" {
+			return nil, fmt.Errorf("storage: dsn is required for %s", cfg.Driver)
+		}
+		s, err := openSQLStorage(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return newBufferedStorage(s, cfg.Buffer), nil
+	default:
+		open, ok := storageDrivers[cfg.Driver]
+		if !ok {
+			return nil, fmt.Errorf("storage: unknown driver %q, expected memory, sqlite or postgres", cfg.Driver)
+		}
+		s, err := open(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("storage: %s: %w", cfg.Driver, err)
+		}
+		return newBufferedStorage(s, cfg.Buffer), nil
+	}
+}
+
+// memoryStorage keeps events in an eventLog, optionally backed by a file, and
+// the most recent results in a ring buffer.
+type memoryStorage struct {
+	events *eventLog
+
+	mu      sync.Mutex
+	results []CheckResult
+	next    int
+	full    bool
+}
+
+func newMemoryStorage(events *eventLog, size int) *memoryStorage {
+	return &memoryStorage{events: events, results: make([]CheckResult, size)}
+}
+
+func (s *memoryStorage) AddResult(r CheckResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results[s.next] = r
+	s.next = (s.next + 1) % len(s.results)
+	if s.next == 0 {
+		s.full = true
+	}
+	return nil
+}
+
+func (s *memoryStorage) AddEvent(e Event) error {
+	s.events.record(e)
+	return nil
+}
+
+func (s *memoryStorage) Results(checks []string, since, until time.Time) ([]CheckResult, error) {
+	s.mu.Lock()
+	all := slices.Clone(s.results[:s.next])
+	if s.full {
+		all = append(slices.Clone(s.results[s.next:]), all...)
+	}
+	s.mu.Unlock()
+
+	out := []CheckResult{}
+	for _, r := range all {
+		if inQuery(r.Check, r.Time, checks, since, until) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (s *memoryStorage) Events(checks []string, since, until time.Time) ([]Event, error) {
+	return s.events.query(checks, since, until), nil
+}
+
+func (s *memoryStorage) Close() error {
+	return s.events.close()
+}
+
+// inQuery reports whether an entry for check at t matches a Storage query.
+func inQuery(check string, t time.Time, checks []string, since, until time.Time) bool {
+	if len(checks) > 0 && !slices.Contains(checks, check) {
+		return false
+	}
+	if !since.IsZero() && t.Before(since) {
+		return false
+	}
+	return until.IsZero() || t.Before(until)
+}
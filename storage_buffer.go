@@ -0,0 +1,207 @@
+package goping
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultStorageBuffer = 10000
+	storageMaxBackoff    = time.Minute
+)
+
+var (
+	storageWritesDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "goping_storage_writes_dropped_total",
+			Help: "Results and events not stored because the storage buffer was full while the backend was unavailable",
+		},
+		[]string{"kind"},
+	)
+
+	storageBuffered = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "goping_storage_buffered_writes",
+			Help: "Results and events waiting in memory to be written to storage",
+		},
+	)
+
+	storageAvailable = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "goping_storage_available",
+			Help: "Whether the last write to storage succeeded",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(storageWritesDropped)
+	prometheus.MustRegister(storageBuffered)
+	prometheus.MustRegister(storageAvailable)
+}
+
+// storageWrite is a result or an event waiting to be written.
+type storageWrite struct {
+	result *CheckResult
+	event  *Event
+}
+
+func (w storageWrite) kind() string {
+	if w.result != nil {
+		return "result"
+	}
+	return "event"
+}
+
+// bufferedStorage writes to a database in the background so checks never
+// wait on it. While the database is unavailable writes queue in memory, up
+// to max, and are retried with backoff; beyond that the oldest are dropped.
+// Queries go straight to the database and don't see queued writes.
+type bufferedStorage struct {
+	Storage
+	max int
+
+	mu      sync.Mutex
+	pending []storageWrite
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newBufferedStorage(s Storage, size int) *bufferedStorage {
+	if size <= 0 {
+		size = defaultStorageBuffer
+	}
+	b := &bufferedStorage{
+		Storage: s,
+		max:     size,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	storageAvailable.Set(1)
+	go b.run()
+	return b
+}
+
+func (b *bufferedStorage) AddResult(r CheckResult) error {
+	b.enqueue(storageWrite{result: &r})
+	return nil
+}
+
+func (b *bufferedStorage) AddEvent(e Event) error {
+	b.enqueue(storageWrite{event: &e})
+	return nil
+}
+
+func (b *bufferedStorage) enqueue(w storageWrite) {
+	b.mu.Lock()
+	if len(b.pending) >= b.max {
+		storageWritesDropped.WithLabelValues(b.pending[0].kind()).Inc()
+		b.pending = b.pending[1:]
+	}
+	b.pending = append(b.pending, w)
+	storageBuffered.Set(float64(len(b.pending)))
+	b.mu.Unlock()
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run flushes whenever there is something to write, and after a failure
+// waits out a growing backoff before trying again.
+func (b *bufferedStorage) run() {
+	defer close(b.done)
+
+	retry := time.NewTimer(time.Hour)
+	retry.Stop()
+	var backoff time.Duration
+	for {
+		wake := b.wake
+		if backoff > 0 {
+			wake = nil
+		}
+		select {
+		case <-b.stop:
+			retry.Stop()
+			return
+		case <-wake:
+		case <-retry.C:
+		}
+
+		n, err := b.flush()
+		if err != nil {
+			if backoff == 0 {
+				storageAvailable.Set(0)
+				logger.Error("Failed to write to storage, buffering until it is available", "error", err)
+			}
+			backoff = min(max(2*backoff, time.Second), storageMaxBackoff)
+			retry.Reset(backoff)
+			continue
+		}
+		if backoff > 0 {
+			storageAvailable.Set(1)
+			logger.Info("Storage available again", "flushed", n)
+			backoff = 0
+		}
+	}
+}
+
+// flush writes queued entries oldest first until the queue is empty or a
+// write fails, in which case the entry stays at the front of the queue.
+func (b *bufferedStorage) flush() (int, error) {
+	n := 0
+	for {
+		b.mu.Lock()
+		if len(b.pending) == 0 {
+			b.mu.Unlock()
+			return n, nil
+		}
+		w := b.pending[0]
+		b.pending = b.pending[1:]
+		b.mu.Unlock()
+
+		var err error
+		if w.result != nil {
+			err = b.Storage.AddResult(*w.result)
+		} else {
+			err = b.Storage.AddEvent(*w.event)
+		}
+
+		b.mu.Lock()
+		if err != nil {
+			if len(b.pending) < b.max {
+				b.pending = append([]storageWrite{w}, b.pending...)
+			} else {
+				storageWritesDropped.WithLabelValues(w.kind()).Inc()
+			}
+		}
+		storageBuffered.Set(float64(len(b.pending)))
+		b.mu.Unlock()
+
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// Close makes a last attempt to write what is queued before closing the
+// database.
+func (b *bufferedStorage) Close() error {
+	close(b.stop)
+	<-b.done
+
+	if _, err := b.flush(); err != nil {
+		b.mu.Lock()
+		n := len(b.pending)
+		b.mu.Unlock()
+		logger.Error("Failed to write buffered results to storage on shutdown", "dropped", n, "error", err)
+	}
+	return b.Storage.Close()
+}
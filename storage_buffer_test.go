@@ -0,0 +1,96 @@
+package goping
+
+import (
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// flakyStorage fails writes while down is set.
+type flakyStorage struct {
+	*memoryStorage
+	down atomic.Bool
+}
+
+func (s *flakyStorage) AddResult(r CheckResult) error {
+	if s.down.Load() {
+		return errors.New("database unavailable")
+	}
+	return s.memoryStorage.AddResult(r)
+}
+
+func (s *flakyStorage) AddEvent(e Event) error {
+	if s.down.Load() {
+		return errors.New("database unavailable")
+	}
+	return s.memoryStorage.AddEvent(e)
+}
+
+func storedChecks(t *testing.T, s Storage) []string {
+	t.Helper()
+	results, err := s.Results(nil, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Check)
+	}
+	return names
+}
+
+func TestBufferedStorageRetries(t *testing.T) {
+	backend := &flakyStorage{memoryStorage: newMemoryStorage(newEventLog(10), 10)}
+	backend.down.Store(true)
+	dropped := testutil.ToFloat64(storageWritesDropped.WithLabelValues("result"))
+
+	b := newBufferedStorage(backend, 2)
+	defer b.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		b.AddResult(CheckResult{Check: name})
+	}
+	waitFor(t, "storage to be marked unavailable", func() bool { return testutil.ToFloat64(storageAvailable) == 0 })
+
+	// Only two fit, so the oldest was dropped.
+	if got := testutil.ToFloat64(storageWritesDropped.WithLabelValues("result")) - dropped; got != 1 {
+		t.Errorf("%v results dropped, want 1", got)
+	}
+	if got := testutil.ToFloat64(storageBuffered); got != 2 {
+		t.Errorf("%v writes buffered, want 2", got)
+	}
+
+	backend.down.Store(false)
+	waitFor(t, "the buffer to be flushed after the backoff", func() bool { return testutil.ToFloat64(storageBuffered) == 0 })
+	if got := storedChecks(t, backend); !slices.Equal(got, []string{"b", "c"}) {
+		t.Errorf("stored %q, want the two newest in order", got)
+	}
+	if got := testutil.ToFloat64(storageAvailable); got != 1 {
+		t.Errorf("storage available = %v, want 1", got)
+	}
+}
+
+func TestBufferedStorageCloseFlushes(t *testing.T) {
+	backend := &flakyStorage{memoryStorage: newMemoryStorage(newEventLog(10), 10)}
+	backend.down.Store(true)
+
+	b := newBufferedStorage(backend, 10)
+	b.AddResult(CheckResult{Check: "a"})
+	b.AddEvent(Event{Check: "a", From: "up", To: "down"})
+	waitFor(t, "storage to be marked unavailable", func() bool { return testutil.ToFloat64(storageAvailable) == 0 })
+
+	// Close doesn't wait for the backoff to make its last attempt.
+	backend.down.Store(false)
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedChecks(t, backend); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("stored results %q, want the buffered one", got)
+	}
+	if events, _ := backend.Events(nil, time.Time{}, time.Time{}); len(events) != 1 {
+		t.Errorf("stored events %+v, want the buffered one", events)
+	}
+}
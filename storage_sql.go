@@ -0,0 +1,247 @@
+package goping
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+const sqlTimeout = 5 * time.Second
+
+// sqlStorage keeps results and events in SQLite or Postgres. Times are stored
+// as Unix nanoseconds so both databases compare and index them the same way.
+type sqlStorage struct {
+	db        *sql.DB
+	driver    string
+	retention time.Duration
+
+	// migrated is set once the schema exists. Creating it is retried on
+	// each use, so goping starts even while the database is unreachable.
+	mu       sync.Mutex
+	migrated bool
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS results (
+		time_ns BIGINT NOT NULL,
+		check_name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		tags TEXT NOT NULL,
+		up BOOLEAN NOT NULL,
+		duration_seconds DOUBLE PRECISION NOT NULL,
+		error TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS results_check_time ON results (check_name, time_ns)`,
+	`CREATE TABLE IF NOT EXISTS events (
+		time_ns BIGINT NOT NULL,
+		check_name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		from_state TEXT NOT NULL,
+		to_state TEXT NOT NULL,
+		error TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS events_check_time ON events (check_name, time_ns)`,
+}
+
+func openSQLStorage(cfg StorageConfig) (*sqlStorage, error) {
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+	if cfg.Driver == "sqlite" {
+		// SQLite allows a single writer; more connections only contend for
+		// the lock.
+		db.SetMaxOpenConns(1)
+	}
+
+	s := &sqlStorage{db: db, driver: cfg.Driver, retention: cfg.Retention, stop: make(chan struct{})}
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	if err := s.migrate(ctx); err != nil {
+		logger.Warn("Storage unavailable, will retry", "driver", cfg.Driver, "error", err)
+	}
+	if s.retention > 0 {
+		s.done.Add(1)
+		go s.prune()
+	}
+	return s, nil
+}
+
+// migrate creates the tables and indexes if that hasn't been done yet.
+func (s *sqlStorage) migrate(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.migrated {
+		return nil
+	}
+	for _, stmt := range sqlSchema {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+	s.migrated = true
+	return nil
+}
+
+// rebind rewrites ? placeholders to Postgres' $n.
+func (s *sqlStorage) rebind(query string) string {
+	if s.driver != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (s *sqlStorage) exec(query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	if err := s.migrate(ctx); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, s.rebind(query), args...)
+	return err
+}
+
+func (s *sqlStorage) AddResult(r CheckResult) error {
+	tags, err := json.Marshal(r.Tags)
+	if err != nil {
+		return err
+	}
+	return s.exec(`INSERT INTO results (time_ns, check_name, type, tags, up, duration_seconds, error) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.Time.UnixNano(), r.Check, r.Type, string(tags), r.Up, r.Duration, r.Error)
+}
+
+func (s *sqlStorage) AddEvent(e Event) error {
+	return s.exec(`INSERT INTO events (time_ns, check_name, type, from_state, to_state, error) VALUES (?, ?, ?, ?, ?, ?)`,
+		e.Time.UnixNano(), e.Check, e.Type, e.From, e.To, e.Error)
+}
+
+// where builds the WHERE clause shared by Results and Events.
+func where(checks []string, since, until time.Time) (string, []any) {
+	var conds []string
+	var args []any
+	if len(checks) > 0 {
+		conds = append(conds, "check_name IN (?"+strings.Repeat(", ?", len(checks)-1)+")")
+		for _, c := range checks {
+			args = append(args, c)
+		}
+	}
+	if !since.IsZero() {
+		conds = append(conds, "time_ns >= ?")
+		args = append(args, since.UnixNano())
+	}
+	if !until.IsZero() {
+		conds = append(conds, "time_ns < ?")
+		args = append(args, until.UnixNano())
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (s *sqlStorage) Results(checks []string, since, until time.Time) ([]CheckResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	if err := s.migrate(ctx); err != nil {
+		return nil, err
+	}
+
+	cond, args := where(checks, since, until)
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT time_ns, check_name, type, tags, up, duration_seconds, error FROM results`+cond+` ORDER BY time_ns`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []CheckResult{}
+	for rows.Next() {
+		var r CheckResult
+		var ns int64
+		var tags string
+		if err := rows.Scan(&ns, &r.Check, &r.Type, &tags, &r.Up, &r.Duration, &r.Error); err != nil {
+			return nil, err
+		}
+		r.Time = time.Unix(0, ns)
+		if err := json.Unmarshal([]byte(tags), &r.Tags); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStorage) Events(checks []string, since, until time.Time) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sqlTimeout)
+	defer cancel()
+	if err := s.migrate(ctx); err != nil {
+		return nil, err
+	}
+
+	cond, args := where(checks, since, until)
+	rows, err := s.db.QueryContext(ctx, s.rebind(`SELECT time_ns, check_name, type, from_state, to_state, error FROM events`+cond+` ORDER BY time_ns`), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Event{}
+	for rows.Next() {
+		var e Event
+		var ns int64
+		if err := rows.Scan(&ns, &e.Check, &e.Type, &e.From, &e.To, &e.Error); err != nil {
+			return nil, err
+		}
+		e.Time = time.Unix(0, ns)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// prune deletes results and events older than the retention period once an
+// hour.
+func (s *sqlStorage) prune() {
+	defer s.done.Done()
+
+	ticker := timeSource.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		cutoff := timeSource.Now().Add(-s.retention).UnixNano()
+		for _, table := range []string{"results", "events"} {
+			if err := s.exec(`DELETE FROM `+table+` WHERE time_ns < ?`, cutoff); err != nil {
+				logger.Error("Failed to prune storage", "table", table, "error", err)
+			}
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.Chan():
+		}
+	}
+}
+
+func (s *sqlStorage) Close() error {
+	close(s.stop)
+	s.done.Wait()
+	return s.db.Close()
+}
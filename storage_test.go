@@ -0,0 +1,69 @@
+package goping
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegisterStorage(t *testing.T) {
+	backend := newMemoryStorage(newEventLog(10), 10)
+	var got StorageConfig
+	RegisterStorage("test", func(cfg StorageConfig) (Storage, error) {
+		got = cfg
+		return backend, nil
+	})
+	t.Cleanup(func() { delete(storageDrivers, "test") })
+
+	cfg := StorageConfig{Driver: "test", DSN: "table=results"}
+	s, err := openStorage(cfg, EventsConfig{})
+	if err != nil {
+		t.Fatalf("openStorage: %v", err)
+	}
+	if got != cfg {
+		t.Errorf("opener got %+v, want %+v", got, cfg)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.AddResult(CheckResult{Time: now, Check: "web", Up: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddEvent(Event{Time: now, Check: "web", From: "unknown", To: "up"}); err != nil {
+		t.Fatal(err)
+	}
+	// Close writes whatever is still buffered.
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	results, _ := backend.Results(nil, time.Time{}, time.Time{})
+	if len(results) != 1 || results[0].Check != "web" {
+		t.Errorf("backend has results %+v, want the one written", results)
+	}
+	events, _ := backend.Events(nil, time.Time{}, time.Time{})
+	if len(events) != 1 || events[0].To != "up" {
+		t.Errorf("backend has events %+v, want the one written", events)
+	}
+}
+
+func TestRegisterStorageTaken(t *testing.T) {
+	open := func(StorageConfig) (Storage, error) { return nil, nil }
+	RegisterStorage("test", open)
+	t.Cleanup(func() { delete(storageDrivers, "test") })
+
+	for _, name := range []string{"test", "sqlite", "memory", ""} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterStorage(%q) didn't panic", name)
+				}
+			}()
+			RegisterStorage(name, open)
+		})
+	}
+}
+
+func TestOpenStorageUnknownDriver(t *testing.T) {
+	if _, err := openStorage(StorageConfig{Driver: "dynamodb"}, EventsConfig{}); err == nil {
+		t.Error("openStorage with an unregistered driver succeeded")
+	}
+}
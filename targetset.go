@@ -0,0 +1,163 @@
+package goping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// targetSet holds the checks goping runs. Targets come from the config file
+// or are added at runtime through the API; only runtime targets can be
+// removed again, and they are what a snapshot carries to another instance.
+type targetSet struct {
+	mu      sync.RWMutex
+	targets []*target
+	byName  map[string]*target
+
+	// ctx is set once checks are started; targets added afterwards are
+	// scheduled immediately.
+	ctx context.Context
+}
+
+func newTargetSet(targets []*target) *targetSet {
+	return &targetSet{targets: targets, byName: targetsByName(targets)}
+}
+
+func (s *targetSet) list() []*target {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.targets)
+}
+
+func (s *targetSet) get(name string) (*target, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.byName[name]
+	return t, ok
+}
+
+func (s *targetSet) start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ctx = ctx
+	for _, t := range s.targets {
+		s.startLocked(t)
+	}
+}
+
+// ready reports whether checks have been started and goping isn't shutting
+// down.
+func (s *targetSet) ready() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ctx != nil && s.ctx.Err() == nil
+}
+
+func (s *targetSet) startLocked(t *target) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	t.stop = cancel
+
+	paused := 0.0
+	if t.isPaused(timeSource.Now()) {
+		paused = 1
+	}
+	checkPaused.WithLabelValues(t.cfg.Name).Set(paused)
+	checksMissed.WithLabelValues(t.cfg.Name)
+
+	logger.Info("Scheduling check", "check", t.cfg.Name, "type", t.cfg.Type, "interval", t.cfg.Interval)
+	go t.schedule(ctx)
+}
+
+// errDuplicateTarget is returned by add for a name that is already taken.
+var errDuplicateTarget = errors.New("duplicate name")
+
+// add creates a runtime target from cfg, applying the same defaults and
+// validation as the config file, and schedules it if checks are running.
+func (s *targetSet) add(cfg CheckConfig) (*target, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if err := applyCheckDefaults(&cfg); err != nil {
+		return nil, err
+	}
+
+	ch, err := newChecker(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byName[cfg.Name]; ok {
+		return nil, fmt.Errorf("check %q: %w", cfg.Name, errDuplicateTarget)
+	}
+	if c, ok := ch.(*compositeChecker); ok {
+		if err := c.resolve(s.byName); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &target{cfg: cfg, checker: ch, runtime: true}
+	s.targets = append(s.targets, t)
+	s.byName[cfg.Name] = t
+	if s.ctx != nil {
+		s.startLocked(t)
+	}
+
+	logger.Info("Target added", "check", cfg.Name, "type", cfg.Type)
+	return t, nil
+}
+
+// checkMetrics are all the metrics labelled by check, whose series are
+// deleted when a target is removed. New ones must be added here.
+var checkMetrics = []interface {
+	DeletePartialMatch(prometheus.Labels) int
+}{
+	checkUp, checkDuration, checkRunsTotal, checksMissed, checkPaused,
+	checkRetries, checkErrors,
+	checkValue, checkValueErrors,
+	throughputMbps, fallbackActive, contentAge, redirectChainChanges,
+	dnsChanges, dnsAddresses, dnsResolverAgrees,
+	canaryLatency, canaryStatusCode,
+	sshHostKeyChanges,
+	alertsSilenced, alertsCollapsed, alertsDeployHeld,
+}
+
+// remove stops and deletes a runtime target. Targets from the config file
+// and targets still referenced by a composite check cannot be removed.
+func (s *targetSet) remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.byName[name]
+	if !ok {
+		return fmt.Errorf("check %q not found", name)
+	}
+	if !t.runtime {
+		return fmt.Errorf("check %q is defined in the config file", name)
+	}
+	for _, other := range s.targets {
+		if c, ok := other.checker.(*compositeChecker); ok && c.references(name) {
+			return fmt.Errorf("check %q is referenced by composite check %q", name, other.cfg.Name)
+		}
+	}
+
+	if t.stop != nil {
+		t.stop()
+	}
+	delete(s.byName, name)
+	s.targets = slices.DeleteFunc(s.targets, func(o *target) bool { return o == t })
+
+	for _, m := range checkMetrics {
+		m.DeletePartialMatch(prometheus.Labels{"check": t.cfg.Name})
+	}
+
+	logger.Info("Target removed", "check", name)
+	return nil
+}
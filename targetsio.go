@@ -0,0 +1,376 @@
+package goping
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const targetsUsage = `usage: goping targets import [flags] FILE
+       goping targets export [flags]
+
+Targets are read and written as CSV with one target per row and a header
+naming the fields as in the config file. Nested fields use dotted columns
+such as tags.env or throughput.min_mbps, and lists are written in YAML flow
+syntax such as [https://a.example.com, https://b.example.com].`
+
+// runTargets implements `goping targets`, which manages the runtime targets
+// of a running instance through its admin API.
+func runTargets(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, targetsUsage)
+		return 2
+	}
+	switch args[0] {
+	case "import":
+		return runTargetsImport(args[1:])
+	case "export":
+		return runTargetsExport(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, targetsUsage)
+		return 2
+	}
+}
+
+// adminClient makes requests to the API of a running goping.
+type adminClient struct {
+	url   string
+	token string
+}
+
+func adminFlags(fs *flag.FlagSet) *adminClient {
+	c := &adminClient{}
+	fs.StringVar(&c.url, "url", "http://localhost:8080", "base URL of the running goping")
+	fs.StringVar(&c.token, "token", "", "API token (default $API_TOKEN)")
+	return c
+}
+
+// apiError is a response from the API with an error status.
+type apiError struct {
+	status int
+	msg    string
+}
+
+func (e *apiError) Error() string { return e.msg }
+
+func (c *adminClient) do(method, path string, body []byte) ([]byte, error) {
+	if c.token == "" {
+		c.token = getEnv("API_TOKEN")
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.url, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/yaml")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		var e struct {
+			Error string `json:"error"`
+		}
+		if yaml.Unmarshal(data, &e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		return nil, &apiError{status: resp.StatusCode, msg: resp.Status + ": " + msg}
+	}
+	return data, nil
+}
+
+func runTargetsImport(args []string) int {
+	fs := flag.NewFlagSet("targets import", flag.ExitOnError)
+	api := adminFlags(fs)
+	replace := fs.Bool("replace", false, "replace runtime targets that already exist instead of failing")
+	dryRun := fs.Bool("dry-run", false, "print the targets as YAML instead of importing them")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, targetsUsage)
+		return 2
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer f.Close()
+
+	rows, err := readTargetsCSV(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", fs.Arg(0), err)
+		return 1
+	}
+
+	failed := 0
+	for _, row := range rows {
+		body, err := yaml.Marshal(row.target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", row.line, err)
+			failed++
+			continue
+		}
+		if *dryRun {
+			fmt.Printf("---\n%s", body)
+			continue
+		}
+
+		name := fmt.Sprint(row.target["name"])
+		_, err = api.do(http.MethodPost, "/api/targets", body)
+		var ae *apiError
+		if errors.As(err, &ae) && ae.status == http.StatusConflict && *replace {
+			if _, err = api.do(http.MethodDelete, "/api/targets/"+url.PathEscape(name), nil); err == nil {
+				_, err = api.do(http.MethodPost, "/api/targets", body)
+			}
+		}
+		if err != nil {
+			fmt.Printf("FAIL %s (line %d): %v\n", name, row.line, err)
+			failed++
+			continue
+		}
+		fmt.Printf("ok   %s\n", name)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runTargetsExport(args []string) int {
+	fs := flag.NewFlagSet("targets export", flag.ExitOnError)
+	api := adminFlags(fs)
+	runtimeOnly := fs.Bool("runtime", false, "export only targets added through the API")
+	outPath := fs.String("o", "", "write the targets here instead of stdout")
+	fs.Parse(args)
+
+	path := "/api/targets"
+	if *runtimeOnly {
+		path += "?runtime=true"
+	}
+	data, err := api.do(http.MethodGet, path, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var resp struct {
+		Targets []map[string]any `yaml:"targets"`
+	}
+	if err := yaml.Unmarshal(data, &resp); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := writeTargetsCSV(out, resp.Targets); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *outPath != "" {
+		fmt.Fprintf(os.Stderr, "Wrote %d targets to %s\n", len(resp.Targets), *outPath)
+	}
+	return 0
+}
+
+type csvTarget struct {
+	line   int
+	target map[string]any
+}
+
+// readTargetsCSV turns each row into a target as it would appear in the
+// config file. Empty cells are left out, so columns only some targets use
+// can be left blank for the others.
+func readTargetsCSV(r io.Reader) ([]csvTarget, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no header row")
+	}
+
+	header := records[0]
+	if !slices.Contains(header, "name") {
+		return nil, fmt.Errorf("header has no name column")
+	}
+
+	var out []csvTarget
+	for i, rec := range records[1:] {
+		t := map[string]any{}
+		for j, cell := range rec {
+			cell = strings.TrimSpace(cell)
+			if cell == "" || j >= len(header) {
+				continue
+			}
+			path := strings.Split(strings.TrimSpace(header[j]), ".")
+			setPath(t, path, parseCell(columnType(path), cell))
+		}
+		if len(t) > 0 {
+			out = append(out, csvTarget{line: i + 2, target: t})
+		}
+	}
+	return out, nil
+}
+
+// columnType returns the type of the CheckConfig field a column sets, or nil
+// if there is no such field.
+func columnType(path []string) reflect.Type {
+	t := reflect.TypeFor[CheckConfig]()
+	for _, key := range path {
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := yamlField(t, key)
+			if !ok {
+				return nil
+			}
+			t = f.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil
+		}
+	}
+	return t
+}
+
+func yamlField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ","); tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// parseCell decodes a cell as YAML into the type of its field, so numbers,
+// booleans, durations and flow-style lists and maps reach the API typed.
+// Text fields and unknown columns are kept as written, even when they look
+// like numbers, and so are cells that don't decode, for the API to reject.
+func parseCell(t reflect.Type, cell string) any {
+	if t == nil || t.Kind() == reflect.String {
+		return cell
+	}
+	v := reflect.New(t)
+	if err := yaml.Unmarshal([]byte(cell), v.Interface()); err != nil {
+		return cell
+	}
+	return v.Elem().Interface()
+}
+
+func setPath(m map[string]any, path []string, v any) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := m[key].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			m[key] = child
+		}
+		m = child
+	}
+	m[path[len(path)-1]] = v
+}
+
+// writeTargetsCSV writes targets with name and type first and the other
+// columns sorted.
+func writeTargetsCSV(w io.Writer, targets []map[string]any) error {
+	rows := make([]map[string]string, len(targets))
+	seen := map[string]bool{}
+	for i, t := range targets {
+		rows[i] = map[string]string{}
+		if err := flatten(rows[i], "", t); err != nil {
+			return err
+		}
+		for col := range rows[i] {
+			seen[col] = true
+		}
+	}
+
+	header := []string{"name", "type"}
+	var rest []string
+	for col := range seen {
+		if col != "name" && col != "type" {
+			rest = append(rest, col)
+		}
+	}
+	slices.Sort(rest)
+	header = append(header, rest...)
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, row := range rows {
+		rec := make([]string, len(header))
+		for i, col := range header {
+			rec[i] = row[col]
+		}
+		cw.Write(rec)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// flatten turns nested maps into dotted columns and writes anything else
+// that isn't a scalar in YAML flow syntax.
+func flatten(row map[string]string, prefix string, v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if err := flatten(row, prefix+k+".", child); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		var node yaml.Node
+		if err := node.Encode(v); err != nil {
+			return err
+		}
+		node.Style = yaml.FlowStyle
+		data, err := yaml.Marshal(&node)
+		if err != nil {
+			return err
+		}
+		row[strings.TrimSuffix(prefix, ".")] = strings.TrimSpace(string(data))
+		return nil
+	case nil:
+		return nil
+	default:
+		row[strings.TrimSuffix(prefix, ".")] = fmt.Sprint(v)
+		return nil
+	}
+}
@@ -0,0 +1,106 @@
+package goping
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTargetsCSVRoundTrip(t *testing.T) {
+	want := []CheckConfig{
+		{
+			Name:         "007",
+			Type:         "http",
+			URL:          "https://example.com/?page=1_000",
+			Interval:     30 * time.Second,
+			Timeout:      5 * time.Second,
+			Retries:      2,
+			RetryBackoff: 1500 * time.Millisecond,
+			Trace:        true,
+			Tags:         map[string]string{"build": "0x1F", "price": "12.50", "count": "1_000", "enabled": "true", "empty": "null"},
+			Fallbacks:    []string{"https://b.example.com", "0123"},
+			Throughput:   &ThroughputConfig{MinMbps: 12.5},
+			Metrics:      []JSONMetricConfig{{Name: "depth", Path: "$.queue.depth"}},
+			Redirect:     &RedirectConfig{HTTPS: true, Host: "www.example.com"},
+		},
+		{
+			Name:     "1e3",
+			Type:     "listen",
+			Port:     8080,
+			Protocol: "tcp",
+			Interval: time.Minute,
+			Timeout:  10 * time.Second,
+		},
+	}
+
+	// Exported as the API returns them.
+	data, err := yaml.Marshal(map[string]any{"targets": want})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Targets []map[string]any `yaml:"targets"`
+	}
+	if err := yaml.Unmarshal(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	var csv bytes.Buffer
+	if err := writeTargetsCSV(&csv, resp.Targets); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := readTargetsCSV(&csv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []CheckConfig
+	for _, row := range rows {
+		// Imported as the API decodes them.
+		body, err := yaml.Marshal(row.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c CheckConfig
+		dec := yaml.NewDecoder(bytes.NewReader(body))
+		dec.KnownFields(true)
+		if err := dec.Decode(&c); err != nil {
+			t.Fatalf("line %d: %v\n%s", row.line, err, body)
+		}
+		got = append(got, c)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip through\n%s\ngot  %+v\nwant %+v", data, got, want)
+	}
+}
+
+func TestParseCell(t *testing.T) {
+	tests := []struct {
+		column string
+		cell   string
+		want   any
+	}{
+		{"name", "007", "007"},
+		{"url", "0x1F", "0x1F"},
+		{"tags.version", "12.50", "12.50"},
+		{"tags.enabled", "true", "true"},
+		{"unknown", "1_000", "1_000"},
+		{"retries", "3", 3},
+		{"retries", "three", "three"},
+		{"trace", "true", true},
+		{"interval", "90s", 90 * time.Second},
+		{"throughput.min_mbps", "12.50", 12.5},
+		{"fallback_urls", "[https://a.example.com, 007]", []string{"https://a.example.com", "007"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.column+"="+tt.cell, func(t *testing.T) {
+			got := parseCell(columnType(strings.Split(tt.column, ".")), tt.cell)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCell = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
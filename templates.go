@@ -0,0 +1,158 @@
+package goping
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	// The Docker image has no zoneinfo; embed it for the tz function.
+	_ "time/tzdata"
+)
+
+// templateFuncs are available in notifier templates and the dashboard.
+var templateFuncs = map[string]any{
+	"humanizeDuration": humanizeDuration,
+	"humanizeBytes":    humanizeBytes,
+	"since": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return formatDuration(timeSource.Now().Sub(t))
+	},
+	"time": func(t time.Time) string {
+		return t.Format(time.RFC3339)
+	},
+	"tz":          formatInTimezone,
+	"truncate":    truncate,
+	"truncateURL": truncateURL,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// humanizeDuration formats a duration, or a number of seconds, as "2d 3h",
+// "4m 5s" or "250ms", keeping the two most significant units.
+func humanizeDuration(v any) (string, error) {
+	var d time.Duration
+	switch x := v.(type) {
+	case time.Duration:
+		d = x
+	case float64:
+		d = time.Duration(x * float64(time.Second))
+	case int:
+		d = time.Duration(x) * time.Second
+	case int64:
+		d = time.Duration(x) * time.Second
+	default:
+		return "", fmt.Errorf("humanizeDuration: unsupported type %T", v)
+	}
+	return formatDuration(d), nil
+}
+
+func formatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	if d < time.Second {
+		return sign + d.Round(time.Millisecond).String()
+	}
+
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+	var parts []string
+	for _, u := range units {
+		if n := d / u.size; n > 0 || len(parts) > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", n, u.suffix))
+			d -= n * u.size
+		}
+		if len(parts) == 2 {
+			break
+		}
+	}
+	if len(parts) == 2 && strings.HasPrefix(parts[1], "0") {
+		parts = parts[:1]
+	}
+	return sign + strings.Join(parts, " ")
+}
+
+// humanizeBytes formats a byte count with binary units, as in "1.5 MiB".
+func humanizeBytes(v any) (string, error) {
+	var n float64
+	switch x := v.(type) {
+	case int:
+		n = float64(x)
+	case int64:
+		n = float64(x)
+	case float64:
+		n = x
+	default:
+		return "", fmt.Errorf("humanizeBytes: unsupported type %T", v)
+	}
+
+	if n < 1024 {
+		return fmt.Sprintf("%.0f B", n), nil
+	}
+	for _, unit := range []string{"KiB", "MiB", "GiB", "TiB"} {
+		n /= 1024
+		if n < 1024 || unit == "TiB" {
+			return fmt.Sprintf("%.1f %s", n, unit), nil
+		}
+	}
+	panic("unreachable")
+}
+
+// formatInTimezone formats t in the named IANA time zone, such as
+// "Europe/Berlin".
+func formatInTimezone(name string, t time.Time) (string, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return "", err
+	}
+	return t.In(loc).Format("2006-01-02 15:04:05 MST"), nil
+}
+
+// truncate shortens s to at most n runes, ending in "…" if it was cut.
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// truncateURL drops the scheme, query and fragment of a URL and shortens the
+// rest to n runes by cutting the middle of the path, keeping the host and
+// the last path element readable.
+func truncateURL(n int, s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return truncate(n, s)
+	}
+
+	short := u.Host + u.EscapedPath()
+	r := []rune(short)
+	if len(r) <= n {
+		return short
+	}
+
+	head := len([]rune(u.Host)) + 1
+	tail := n - head - 1
+	if tail <= 0 {
+		return truncate(n, short)
+	}
+	return string(r[:head]) + "…" + string(r[len(r)-tail:])
+}
@@ -0,0 +1,72 @@
+package goping
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+)
+
+// tlsInfo describes the TLS connection of an HTTPS check's last response,
+// so the TLS posture of every target can be audited from /api/status.
+type tlsInfo struct {
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipherSuite"`
+	ALPN        string `json:"alpn,omitempty"`
+
+	// The leaf certificate.
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dnsNames,omitempty"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+
+	ObservedAt time.Time `json:"observedAt"`
+}
+
+func newTLSInfo(cs *tls.ConnectionState, now time.Time) *tlsInfo {
+	info := &tlsInfo{
+		Version:     tls.VersionName(cs.Version),
+		CipherSuite: tls.CipherSuiteName(cs.CipherSuite),
+		ALPN:        cs.NegotiatedProtocol,
+		ObservedAt:  now,
+	}
+	if len(cs.PeerCertificates) > 0 {
+		leaf := cs.PeerCertificates[0]
+		info.Subject = leaf.Subject.String()
+		info.Issuer = leaf.Issuer.String()
+		info.DNSNames = leaf.DNSNames
+		info.NotBefore = leaf.NotBefore
+		info.NotAfter = leaf.NotAfter
+	}
+	return info
+}
+
+// tlsReporter is implemented by checkers that can report the TLS connection
+// of their last response.
+type tlsReporter interface {
+	lastTLS() *tlsInfo
+}
+
+// tlsRecorder keeps the most recently seen TLS connection. It is safe for
+// concurrent use, since the status API reads it while checks run.
+type tlsRecorder struct {
+	mu   sync.Mutex
+	info *tlsInfo
+}
+
+func (r *tlsRecorder) record(cs *tls.ConnectionState) {
+	if cs == nil {
+		return
+	}
+	info := newTLSInfo(cs, timeSource.Now())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.info = info
+}
+
+func (r *tlsRecorder) lastTLS() *tlsInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.info
+}
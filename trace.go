@@ -0,0 +1,127 @@
+package goping
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+type traceKey struct{}
+
+// checkTrace collects the timing breakdown of a single check run for targets
+// with trace enabled, logged as one line when the run completes. All methods
+// are no-ops on a nil trace so checkers can record unconditionally.
+type checkTrace struct {
+	mu sync.Mutex
+	// Phases keep their first-seen order; checks making several requests
+	// report the sum of each phase.
+	phaseOrder []string
+	phases     map[string]time.Duration
+	retries    int
+	bytes      int64
+	assertions []any
+}
+
+func withTrace(ctx context.Context) (context.Context, *checkTrace) {
+	t := &checkTrace{phases: make(map[string]time.Duration)}
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+func traceFrom(ctx context.Context) *checkTrace {
+	t, _ := ctx.Value(traceKey{}).(*checkTrace)
+	return t
+}
+
+func (t *checkTrace) phase(name string, d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.phases[name]; !ok {
+		t.phaseOrder = append(t.phaseOrder, name)
+	}
+	t.phases[name] += d
+}
+
+func (t *checkTrace) retry() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.retries++
+}
+
+func (t *checkTrace) addBytes(n int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bytes += n
+}
+
+// assert records the outcome of a condition the check evaluated.
+func (t *checkTrace) assert(name string, ok bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := "pass"
+	if !ok {
+		result = "fail"
+	}
+	t.assertions = append(t.assertions, slog.String(name, result))
+}
+
+func (t *checkTrace) log(cfg CheckConfig, duration time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	phases := make([]any, 0, len(t.phaseOrder))
+	for _, name := range t.phaseOrder {
+		phases = append(phases, slog.Duration(name, t.phases[name]))
+	}
+
+	attrs := []any{
+		"check", cfg.Name,
+		"type", cfg.Type,
+		"duration", duration,
+		slog.Group("phases", phases...),
+		"retries", t.retries,
+		"response_bytes", t.bytes,
+		slog.Group("assertions", t.assertions...),
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+	}
+	logger.Info("Check trace", attrs...)
+}
+
+// httpClientTrace records DNS, connect, TLS and time-to-first-byte phases of
+// an HTTP request into t.
+func (t *checkTrace) httpClientTrace(start time.Time) *httptrace.ClientTrace {
+	var dnsStart, connectStart, tlsStart time.Time
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.phase("dns", time.Since(dnsStart))
+		},
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(string, string, error) {
+			t.phase("connect", time.Since(connectStart))
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.phase("tls", time.Since(tlsStart))
+		},
+		GotFirstResponseByte: func() {
+			t.phase("ttfb", time.Since(start))
+		},
+	}
+}